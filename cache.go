@@ -0,0 +1,171 @@
+package brief
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// IncludeCache decodes each included file once and clones its nodes into
+// every place it is included.  One cache can be shared by many decoders and
+// is safe for concurrent use.  Included files are keyed by absolute path and
+// indent, so use one cache per Resolver.  A Decoder with Limits or variables
+// does not use its cache, since cached files are not counted against the
+// limits and do not depend on the variables.
+type IncludeCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*included
+	pending map[cacheKey]*flight // files being decoded
+}
+
+// flight of a file being decoded, decoders that include the same file wait
+// for it to be done instead of decoding the file again
+type flight struct {
+	path  string
+	done  chan struct{}
+	entry *included
+	err   error
+	stale bool    // invalidated while it was decoded, so it is not cached
+	waits *flight // the flight its decoder is waiting for
+}
+
+type cacheKey struct {
+	path    string
+	padding int
+}
+
+// NewIncludeCache that is empty
+func NewIncludeCache() *IncludeCache {
+	return &IncludeCache{entries: map[cacheKey]*included{}, pending: map[cacheKey]*flight{}}
+}
+
+// include returns a copy of the cached file or else decodes and caches it
+// a file is decoded once even when it is included by many decoders at once,
+// and a file that fails to decode is not cached.  A cached file is checked
+// by the resolver of the decoder, since it is not opened.
+func (c *IncludeCache) include(dec *Decoder, filename string, padding int) (*included, error) {
+	key := cacheKey{path: absPath(filename), padding: padding}
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		c.mu.Unlock()
+		if err := dec.allow(filename); err != nil {
+			return &included{}, err
+		}
+		return entry.clone(), nil
+	}
+	f, decoding := c.pending[key]
+	if !decoding {
+		f = &flight{path: key.path, done: make(chan struct{})}
+		c.pending[key] = f
+		c.mu.Unlock()
+		f.entry, f.err = dec.decodeInclude(filename, padding, f)
+		c.mu.Lock()
+		if f.err == nil && !f.stale {
+			c.entries[key] = f.entry
+		}
+		delete(c.pending, key)
+		c.mu.Unlock()
+		close(f.done)
+		if f.err != nil {
+			return f.entry, f.err
+		}
+		return f.entry.clone(), nil
+	}
+	if c.cycle(dec, f) {
+		c.mu.Unlock()
+		return &included{}, fmt.Errorf("#include %s includes itself", filename)
+	}
+	if dec.flight != nil {
+		dec.flight.waits = f
+	}
+	c.mu.Unlock()
+	if err := dec.allow(filename); err == nil {
+		<-f.done
+	} else {
+		f = &flight{err: err, entry: &included{}}
+	}
+	if dec.flight != nil {
+		c.mu.Lock()
+		dec.flight.waits = nil
+		c.mu.Unlock()
+	}
+	if f.err != nil {
+		return f.entry, f.err
+	}
+	return f.entry.clone(), nil
+}
+
+// cycle true when the flight waits, directly or through the flights of
+// other decoders, for a file the decoder is decoding, so waiting for it
+// would never end
+func (c *IncludeCache) cycle(dec *Decoder, f *flight) bool {
+	for ; f != nil; f = f.waits {
+		if dec.including(f.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// clone of the cached file for one include
+func (inc *included) clone() *included {
+	nodes := make([]*Node, len(inc.nodes))
+	for i, node := range inc.nodes {
+		nodes[i] = node.Clone()
+	}
+	return &included{nodes: nodes, templates: inc.templates, includes: inc.includes}
+}
+
+// Invalidate the file and the cached files that include it
+// call it when a file changes
+func (c *IncludeCache) Invalidate(filename string) {
+	path := absPath(filename)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if key.path == path || entry.includesPath(path) {
+			delete(c.entries, key)
+		}
+	}
+	// the includes of a file being decoded are not known yet
+	for _, f := range c.pending {
+		f.stale = true
+	}
+}
+
+// Clear every cached file
+func (c *IncludeCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[cacheKey]*included{}
+	for _, f := range c.pending {
+		f.stale = true
+	}
+}
+
+// Len is the number of cached files, a file included with different
+// indents is cached for each
+func (c *IncludeCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// includesPath true if the file included the path, directly or not
+func (inc *included) includesPath(path string) bool {
+	for _, sub := range inc.includes {
+		if absPath(sub.Path) == path {
+			return true
+		}
+	}
+	return false
+}
+
+func absPath(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.Clean(filename)
+	}
+	return abs
+}
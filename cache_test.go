@@ -0,0 +1,161 @@
+package brief_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludeCache(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(text), 0644))
+	}
+	write("header.brief", "header:v1\n")
+	write("nav.brief", "nav\n    #include `header.brief`\n")
+	page := "page\n    #include `header.brief`\n    body\n        #include `header.brief`\n    #include `nav.brief`\n"
+
+	cache := brief.NewIncludeCache()
+	decode := func() *brief.Node {
+		dec := brief.NewDecoder(strings.NewReader(page), 4, dir)
+		dec.Cache = cache
+		nodes, err := dec.Decode()
+		require.NoError(t, err)
+		require.Len(t, nodes, 1)
+		return nodes[0]
+	}
+	first := decode()
+	assert.Equal(t, 3, cache.Len(), "header at two indents and nav")
+	assert.Equal(t, "v1", first.Child("header").Name)
+	assert.Equal(t, "v1", first.Child("body", "header").Name)
+	assert.Equal(t, 8, first.Child("body", "header").Indent)
+	assert.Same(t, first, first.Child("header").Parent)
+
+	second := decode()
+	assert.NotSame(t, first.Child("header"), second.Child("header"), "cached nodes are cloned")
+	second.Child("header").Name = "changed"
+	assert.Equal(t, "v1", decode().Child("header").Name)
+
+	write("header.brief", "header:v2\n")
+	assert.Equal(t, "v1", decode().Child("nav", "header").Name, "still cached")
+	cache.Invalidate(filepath.Join(dir, "header.brief"))
+	assert.Equal(t, 0, cache.Len(), "nav includes the header")
+	fresh := decode()
+	assert.Equal(t, "v2", fresh.Child("header").Name)
+	assert.Equal(t, "v2", fresh.Child("nav", "header").Name)
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Len())
+}
+
+// countResolver counts the files opened, slowly so that decodes overlap
+type countResolver struct {
+	mu     sync.Mutex
+	opened map[string]int
+}
+
+func (r *countResolver) Open(path string) (io.ReadCloser, error) {
+	r.mu.Lock()
+	r.opened[filepath.Base(path)]++
+	r.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	return os.Open(path)
+}
+
+// Check a cached include without opening it
+func (r *countResolver) Check(path string) error {
+	_, err := os.Stat(path)
+	return err
+}
+
+func TestIncludeCacheConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "header.brief"), []byte("header:v1\n"), 0644))
+	paths := make([]string, 16)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("page%d.brief", i))
+		require.NoError(t, os.WriteFile(paths[i], []byte("#include `header.brief`\n"), 0644))
+	}
+	resolver := &countResolver{opened: map[string]int{}}
+	cache := brief.NewIncludeCache()
+	results := brief.DecodeFiles(paths, brief.DecodeOptions{Workers: len(paths), Resolver: resolver, Cache: cache})
+	for _, result := range results {
+		require.NoError(t, result.Err)
+		require.Len(t, result.Nodes, 1)
+		assert.Equal(t, "v1", result.Nodes[0].Name)
+	}
+	assert.Equal(t, 1, resolver.opened["header.brief"], "parsed once")
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.brief"), []byte("a\n    #include `b.brief`\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.brief"), []byte("b\n    #include `a.brief`\n"), 0644))
+	for _, cache := range []*brief.IncludeCache{nil, brief.NewIncludeCache()} {
+		results := brief.DecodeFiles([]string{filepath.Join(dir, "a.brief")}, brief.DecodeOptions{Cache: cache})
+		require.Error(t, results[0].Err)
+		assert.Contains(t, results[0].Err.Error(), "includes itself")
+	}
+}
+
+func TestIncludeCacheRoots(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shared.brief"), []byte("shared\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.brief"), []byte("#include `shared.brief`\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.brief"), []byte("#include `../shared.brief`\n"), 0644))
+	paths := []string{filepath.Join(dir, "a.brief"), filepath.Join(dir, "sub", "b.brief")}
+	results := brief.DecodeFiles(paths, brief.DecodeOptions{Workers: 1, Cache: brief.NewIncludeCache()})
+	require.NoError(t, results[0].Err)
+	require.Error(t, results[1].Err, "a cached include is still confined to the root")
+	assert.Contains(t, results[1].Err.Error(), "outside the root")
+}
+
+// slowResolver opens some files slowly
+type slowResolver map[string]time.Duration
+
+func (r slowResolver) Open(path string) (io.ReadCloser, error) {
+	time.Sleep(r[filepath.Base(path)])
+	return os.Open(path)
+}
+
+func TestIncludeCacheCycle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.brief":    "#include `x.brief`\n",
+		"b.brief":    "#include `wait.brief`\n#include `y.brief`\n",
+		"wait.brief": "",
+		"x.brief":    "x\n#include `y.brief`\n",
+		"y.brief":    "y\n#include `x.brief`\n",
+	}
+	for name, text := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(text), 0644))
+	}
+	// a decodes x while b decodes y, then each includes the other
+	resolver := slowResolver{"x.brief": 100 * time.Millisecond, "wait.brief": 50 * time.Millisecond}
+	paths := []string{filepath.Join(dir, "a.brief"), filepath.Join(dir, "b.brief")}
+	done := make(chan []brief.FileResult, 1)
+	go func() {
+		opts := brief.DecodeOptions{Workers: 2, Resolver: resolver, Cache: brief.NewIncludeCache()}
+		done <- brief.DecodeFiles(paths, opts)
+	}()
+	select {
+	case results := <-done:
+		for _, result := range results {
+			require.Error(t, result.Err, result.Path)
+			assert.Contains(t, result.Err.Error(), "includes itself")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("decoders waiting for each other")
+	}
+}
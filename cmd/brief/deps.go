@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/robbyriverside/brief"
+)
+
+type depsCommand struct {
+	Format   string `short:"f" long:"format" default:"list" choice:"list" choice:"make" choice:"dot" description:"output format"`
+	Absolute bool   `long:"absolute" description:"print absolute paths instead of paths relative to the current directory"`
+	Args     struct {
+		Files []string `positional-arg-name:"file" required:"1" description:"brief files"`
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute deps prints the include graph of the files
+func (cmd *depsCommand) Execute(args []string) error {
+	vars, err := defines()
+	if err != nil {
+		return err
+	}
+	results := brief.DecodeFiles(cmd.Args.Files, brief.DecodeOptions{Workers: opt.Jobs, Vars: vars})
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.Path, result.Err)
+			failed++
+		}
+	}
+	graph := brief.IncludeGraph(results)
+	if !cmd.Absolute {
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		graph = graph.Rel(dir)
+	}
+	switch cmd.Format {
+	case "make":
+		err = graph.WriteMake(os.Stdout)
+	case "dot":
+		err = graph.WriteDOT(os.Stdout)
+	default:
+		err = graph.WriteList(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("files failed: %d", failed)
+	}
+	return nil
+}
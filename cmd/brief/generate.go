@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/robbyriverside/brief"
+)
+
+type generateCommand struct {
+	Manifest  string   `short:"m" long:"manifest" required:"true" description:"manifest file mapping nodes to output files"`
+	Templates string   `short:"t" long:"templates" description:"directory of templates (default from the manifest)"`
+	Output    string   `short:"o" long:"output" default:"." description:"output directory"`
+	Data      []string `long:"data" description:"extra key=value for the data template function"`
+	DryRun    bool     `short:"n" long:"dry-run" description:"list the changes without writing them"`
+	Args      struct {
+		Files []string `positional-arg-name:"file" required:"1" description:"brief files"`
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute generate writes the manifest outputs for the roots of every file
+func (cmd *generateCommand) Execute(args []string) error {
+	m, err := brief.LoadManifest(cmd.Manifest)
+	if err != nil {
+		return err
+	}
+	if len(cmd.Templates) > 0 {
+		m.Templates = cmd.Templates
+	}
+	r := brief.NewRenderer()
+	if err := r.ParseDir(m.Templates); err != nil {
+		return err
+	}
+	if err := parseData(r.Data, cmd.Data); err != nil {
+		return err
+	}
+	vars, err := defines()
+	if err != nil {
+		return err
+	}
+	results := make([]brief.FileResult, 0, len(cmd.Args.Files))
+	for _, file := range cmd.Args.Files {
+		dec, err := brief.NewFileDecoder(file)
+		if err != nil {
+			return err
+		}
+		dec.Debug = opt.Verbose
+		dec.Vars = vars
+		nodes, err := dec.Decode()
+		if err != nil {
+			return err
+		}
+		results = append(results, brief.FileResult{Path: file, Nodes: nodes, Templates: dec.Templates})
+	}
+	gen, err := m.GenerateFiles(r, results, cmd.Output, cmd.DryRun)
+	if err != nil {
+		return err
+	}
+	for _, name := range gen.Written {
+		fmt.Println("write", name)
+	}
+	for _, name := range gen.Removed {
+		fmt.Println("remove", name)
+	}
+	if opt.Verbose {
+		for _, name := range gen.Unchanged {
+			fmt.Println("unchanged", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"github.com/robbyriverside/brief"
+)
+
+type genGoCommand struct {
+	Schema  string `short:"s" long:"schema" description:"schema file, instead of inferring from sample files"`
+	Package string `short:"p" long:"package" default:"spec" description:"Go package name"`
+	Output  string `short:"o" long:"output" description:"output file (default stdout)"`
+	Args    struct {
+		Files []string `positional-arg-name:"sample" description:"sample brief files"`
+	} `positional-args:"true"`
+}
+
+// Execute gen-go writes Go types for a schema or for sample files
+func (cmd *genGoCommand) Execute(args []string) error {
+	schema, err := loadOrInferSchema(cmd.Schema, cmd.Args.Files)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := brief.GenerateGo(&out, schema, cmd.Package); err != nil {
+		return err
+	}
+	if len(cmd.Output) == 0 {
+		_, err = os.Stdout.Write(out.Bytes())
+		return err
+	}
+	return os.WriteFile(cmd.Output, out.Bytes(), 0644)
+}
+
+// loadOrInferSchema loads the schema file or else infers a schema from the sample files
+func loadOrInferSchema(schemaFile string, samples []string) (*brief.Schema, error) {
+	if len(schemaFile) > 0 {
+		return brief.LoadSchema(schemaFile)
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("a schema or sample files are required")
+	}
+	nodes := make([]*brief.Node, 0)
+	for _, filename := range samples {
+		found, err := brief.DecodeFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, found...)
+	}
+	return brief.InferSchema(nodes), nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import "os"
+
+type inferCommand struct {
+	Name   string `short:"n" long:"name" description:"name of the schema"`
+	Output string `short:"o" long:"output" description:"output file (default stdout)"`
+	Args   struct {
+		Files []string `positional-arg-name:"file" required:"1" description:"sample brief files"`
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute infer writes a schema in brief describing the sample files
+func (cmd *inferCommand) Execute(args []string) error {
+	schema, err := loadOrInferSchema("", cmd.Args.Files)
+	if err != nil {
+		return err
+	}
+	schema.Name = cmd.Name
+	out := schema.Encode()
+	if len(cmd.Output) == 0 {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return os.WriteFile(cmd.Output, out, 0644)
+}
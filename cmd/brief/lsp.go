@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+
+	"github.com/robbyriverside/brief/lsp"
+)
+
+type lspCommand struct{}
+
+// Execute lsp serves the Language Server Protocol over stdin and stdout
+func (cmd *lspCommand) Execute(args []string) error {
+	return lsp.NewServer(os.Stdin, os.Stdout).Run()
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"os"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/robbyriverside/brief"
@@ -12,42 +12,101 @@ import (
 var SemVer = "unknown"
 
 type options struct {
-	Args struct {
-		File string `positional-arg-name:"file" description:"brief file"`
-	} `positional-args:"true" required:"true"`
-	Verbose bool `short:"v" long:"verbose" description:"verbose output"`
-	Version bool `long:"version" description:"describe version"`
+	Verbose  bool            `short:"v" long:"verbose" description:"verbose output"`
+	Version  bool            `long:"version" description:"describe version"`
+	Format   string          `short:"f" long:"format" default:"brief" choice:"brief" choice:"xml" choice:"json" description:"output format"`
+	Jobs     int             `short:"j" long:"jobs" description:"files decoded at once (default number of CPUs)"`
+	Define   []string        `short:"D" long:"define" description:"name=value variable for ${name} and #if"`
+	Validate validateCommand `command:"validate" description:"validate brief files against a schema"`
+	GenGo    genGoCommand    `command:"gen-go" description:"generate Go types from a schema or sample files"`
+	Infer    inferCommand    `command:"infer" description:"infer a schema from sample files"`
+	XSD      xsdCommand      `command:"xsd" description:"derive an XML Schema or JSON Schema from a schema or sample files"`
+	Render   renderCommand   `command:"render" description:"render brief files with a directory of templates"`
+	Generate generateCommand `command:"generate" description:"generate files from a manifest of templates"`
+	LSP      lspCommand      `command:"lsp" description:"serve the Language Server Protocol over stdio"`
+	Watch    watchCommand    `command:"watch" description:"run an action again whenever brief files or their includes change"`
+	Deps     depsCommand     `command:"deps" description:"print the include dependencies of brief files"`
 }
 
+var opt options
+
 func main() {
-	opt := &options{}
-	parser := flags.NewParser(opt, flags.Default)
+	parser := flags.NewParser(&opt, flags.Default)
 	parser.Name = "brief"
+	parser.Usage = "[OPTIONS] [files...]"
+	parser.SubcommandsOptional = true
 
-	if _, err := parser.Parse(); err != nil {
+	args, err := parser.Parse()
+	if err != nil {
 		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
 			return
 		}
+		os.Exit(1)
+	}
+	if parser.Active != nil {
+		return
 	}
 	if opt.Version {
 		fmt.Println("brief", SemVer)
 		return
 	}
-	dec, err := brief.NewFileDecoder(opt.Args.File)
-	if err != nil {
-		log.Fatal(err)
+	if len(args) == 0 {
+		parser.WriteHelp(os.Stderr)
+		os.Exit(1)
 	}
-	dec.Debug = opt.Verbose
-	nodes, err := dec.Decode()
+	vars, err := defines()
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+	workers := opt.Jobs
+	if opt.Verbose {
+		workers = 1 // one trace at a time
+	}
+	failed := false
+	for _, result := range brief.DecodeFiles(args, brief.DecodeOptions{Workers: workers, Vars: vars, Debug: opt.Verbose}) {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.Path, result.Err)
+			failed = true
+			continue
+		}
+		if err := write(result.Nodes); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.Path, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// write nodes to stdout in the output format, the first error ends the write
+func write(nodes []*brief.Node) error {
 	for _, node := range nodes {
-		if dec.Debug {
+		var err error
+		if opt.Verbose {
 			fmt.Println(node)
 			continue
 		}
-		out := node.Encode()
-		fmt.Println(string(out))
+		switch opt.Format {
+		case "xml":
+			err = node.WriteXML(os.Stdout)
+			fmt.Println()
+		case "json":
+			err = node.WriteJSON(os.Stdout)
+		default:
+			out := node.Encode()
+			fmt.Println(string(out))
+		}
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// defines the variables of the -D options
+func defines() (map[string]string, error) {
+	vars := map[string]string{}
+	return vars, parseData(vars, opt.Define)
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/robbyriverside/brief"
+)
+
+// defaultFilename is the --filename default of render
+const defaultFilename = "{{.Type}}{{if .Name}}-{{.Name}}{{end}}"
+
+type renderCommand struct {
+	Templates string   `short:"t" long:"templates" required:"true" description:"directory of templates"`
+	Entry     string   `short:"e" long:"entry" default:"main" description:"entry template name"`
+	Output    string   `short:"o" long:"output" description:"output directory (default stdout)"`
+	Filename  string   `long:"filename" default:"{{.Type}}{{if .Name}}-{{.Name}}{{end}}" description:"template for the output file name of each root element"`
+	Data      []string `long:"data" description:"extra key=value for the data template function"`
+	DryRun    bool     `short:"n" long:"dry-run" description:"list the output files without writing them"`
+	Args      struct {
+		Files []string `positional-arg-name:"file" required:"1" description:"brief files"`
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute render runs the entry template against each root element
+func (cmd *renderCommand) Execute(args []string) error {
+	vars, err := defines()
+	if err != nil {
+		return err
+	}
+	run, err := cmd.renderer()
+	if err != nil {
+		return err
+	}
+	for _, file := range cmd.Args.Files {
+		dec, err := brief.NewFileDecoder(file)
+		if err != nil {
+			return err
+		}
+		dec.Debug = opt.Verbose
+		dec.Vars = vars
+		nodes, err := dec.Decode()
+		if err != nil {
+			return err
+		}
+		if err := run.file(nodes, dec.Templates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rendering of decoded files with the templates of the command
+type rendering struct {
+	cmd      *renderCommand
+	r        *brief.Renderer
+	filename *template.Template
+	paths    map[string]bool // files already rendered, no file is rendered twice
+}
+
+func (cmd *renderCommand) renderer() (*rendering, error) {
+	r := brief.NewRenderer()
+	r.Entry = cmd.Entry
+	if err := r.ParseDir(cmd.Templates); err != nil {
+		return nil, err
+	}
+	if err := parseData(r.Data, cmd.Data); err != nil {
+		return nil, err
+	}
+	filename, err := template.New("filename").Funcs(brief.FuncMap()).Parse(cmd.Filename)
+	if err != nil {
+		return nil, err
+	}
+	return &rendering{cmd: cmd, r: r, filename: filename, paths: map[string]bool{}}, nil
+}
+
+// file renders the root nodes of a file with its #template partials
+func (run *rendering) file(nodes []*brief.Node, templates *template.Template) error {
+	r, err := run.r.WithTemplates(templates)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := run.cmd.render(r, run.filename, node, run.paths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render the node to stdout or to its file in the output directory
+func (cmd *renderCommand) render(r *brief.Renderer, filename *template.Template, node *brief.Node, paths map[string]bool) error {
+	if len(cmd.Output) == 0 {
+		if cmd.DryRun {
+			fmt.Println("stdout")
+			return nil
+		}
+		return r.Render(os.Stdout, node)
+	}
+	var name strings.Builder
+	if err := filename.Execute(&name, node); err != nil {
+		return err
+	}
+	local := filepath.Clean(name.String())
+	if !brief.LocalPath(local) {
+		return fmt.Errorf("%s: path %s is outside the output directory", node.Pos, local)
+	}
+	if paths[local] {
+		return fmt.Errorf("%s: path %s is rendered twice", node.Pos, local)
+	}
+	paths[local] = true
+	path := filepath.Join(cmd.Output, local)
+	if cmd.DryRun {
+		fmt.Println(path)
+		return nil
+	}
+	var out bytes.Buffer
+	if err := r.Render(&out, node); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
+
+// parseData adds key=value pairs to data
+func parseData(data map[string]string, pairs []string) error {
+	for _, pair := range pairs {
+		pos := strings.IndexRune(pair, '=')
+		if pos < 1 {
+			return fmt.Errorf("%q is not key=value", pair)
+		}
+		data[pair[:pos]] = pair[pos+1:]
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/robbyriverside/brief"
+)
+
+type validateCommand struct {
+	Schema    string `short:"s" long:"schema" description:"schema file used instead of the #schema directive"`
+	Normalize bool   `long:"normalize" description:"apply the schema aliases, coercion and defaults before validating"`
+	Args      struct {
+		Files []string `positional-arg-name:"file" required:"1" description:"brief files"`
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute validate prints a file:line:col: diagnostic for each problem
+func (cmd *validateCommand) Execute(args []string) error {
+	vars, err := defines()
+	if err != nil {
+		return err
+	}
+	v := cmd.validator()
+	for _, filename := range cmd.Args.Files {
+		dec, err := brief.NewFileDecoder(filename)
+		if err != nil {
+			v.report(err)
+			continue
+		}
+		dec.Debug = opt.Verbose
+		dec.Vars = vars
+		nodes, err := dec.Decode()
+		if err != nil {
+			v.report(err)
+			continue
+		}
+		v.check(filename, nodes, dec.SchemaFile)
+	}
+	return v.result()
+}
+
+// validator checks decoded files and counts the problems
+type validator struct {
+	cmd      *validateCommand
+	schemas  map[string]*brief.Schema
+	problems int
+}
+
+func (cmd *validateCommand) validator() *validator {
+	return &validator{cmd: cmd, schemas: map[string]*brief.Schema{}}
+}
+
+// report the diagnostics of an error
+func (v *validator) report(err error) {
+	for _, msg := range diagnostics(err) {
+		fmt.Fprintln(os.Stderr, msg)
+		v.problems++
+	}
+}
+
+// check the nodes of a file against the --schema or else its #schema
+func (v *validator) check(filename string, nodes []*brief.Node, schemaFile string) {
+	if len(v.cmd.Schema) > 0 {
+		schemaFile = v.cmd.Schema
+	}
+	if len(schemaFile) == 0 {
+		v.report(fmt.Errorf("%s: no schema, add a #schema directive or use --schema", filename))
+		return
+	}
+	schema, ok := v.schemas[schemaFile]
+	if !ok {
+		var err error
+		schema, err = brief.LoadSchema(schemaFile)
+		if err != nil {
+			v.report(err)
+			return
+		}
+		v.schemas[schemaFile] = schema
+	}
+	if v.cmd.Normalize {
+		if err := schema.Normalize(nodes); err != nil {
+			v.report(err)
+			return
+		}
+	}
+	if vs := brief.Validate(nodes, schema); len(vs) > 0 {
+		v.report(vs)
+	}
+}
+
+// result is an error when there were problems
+func (v *validator) result() error {
+	if v.problems > 0 {
+		return fmt.Errorf("problems found: %d", v.problems)
+	}
+	return nil
+}
+
+// diagnostics formats errors as file:line:col: message
+func diagnostics(err error) []string {
+	var vs brief.Violations
+	if errors.As(err, &vs) {
+		msgs := make([]string, len(vs))
+		for i, v := range vs {
+			msgs[i] = v.Error()
+		}
+		return msgs
+	}
+	var derr *brief.DecodeError
+	if errors.As(err, &derr) {
+		msgs := []string{}
+		for ; derr != nil; derr, _ = derr.Err.(*brief.DecodeError) {
+			msgs = append([]string{fmt.Sprintf("%s: %s", derr.Pos, derr.Msg)}, msgs...)
+		}
+		return msgs
+	}
+	return []string{err.Error()}
+}
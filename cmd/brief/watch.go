@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/robbyriverside/brief"
+)
+
+type watchCommand struct {
+	Action    string        `short:"a" long:"action" default:"convert" choice:"convert" choice:"validate" choice:"render" description:"run on each change"`
+	Schema    string        `short:"s" long:"schema" description:"schema file for validate"`
+	Templates string        `short:"t" long:"templates" description:"directory of templates for render"`
+	Output    string        `short:"o" long:"output" description:"output directory for render (default stdout)"`
+	Data      []string      `long:"data" description:"extra key=value for the data template function"`
+	Interval  time.Duration `long:"interval" default:"250ms" description:"time between checks for changes"`
+	Debounce  time.Duration `long:"debounce" default:"100ms" description:"quiet time after a change before running"`
+	Args      struct {
+		Files []string `positional-arg-name:"file" required:"1" description:"brief files"`
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute watch runs the action for every file and again for the files
+// affected by each change, until interrupted
+func (cmd *watchCommand) Execute(args []string) error {
+	if cmd.Action == "render" && len(cmd.Templates) == 0 {
+		return fmt.Errorf("render needs --templates")
+	}
+	w := brief.NewWatcher(cmd.Args.Files, cmd.run)
+	w.Interval = cmd.Interval
+	w.Debounce = cmd.Debounce
+	w.Options.Cache = brief.NewIncludeCache()
+	vars, err := defines()
+	if err != nil {
+		return err
+	}
+	w.Options.Vars = vars
+	if len(cmd.Schema) > 0 {
+		w.Extra = append(w.Extra, cmd.Schema)
+	}
+	if len(cmd.Templates) > 0 {
+		w.Globs = append(w.Globs, filepath.Join(cmd.Templates, "*"))
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return w.Run(ctx)
+}
+
+// run the action for the decoded files, errors are reported and watching goes on
+func (cmd *watchCommand) run(results []brief.FileResult) {
+	if err := cmd.action(results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// action on the nodes decoded by the watcher, files that failed are reported
+func (cmd *watchCommand) action(results []brief.FileResult) error {
+	decoded := make([]brief.FileResult, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.Path, result.Err)
+			continue
+		}
+		decoded = append(decoded, result)
+	}
+	switch cmd.Action {
+	case "validate":
+		v := (&validateCommand{Schema: cmd.Schema}).validator()
+		for _, result := range decoded {
+			v.check(result.Path, result.Nodes, result.SchemaFile)
+		}
+		return v.result()
+	case "render":
+		render := &renderCommand{
+			Templates: cmd.Templates,
+			Entry:     brief.DefaultEntry,
+			Output:    cmd.Output,
+			Filename:  defaultFilename,
+			Data:      cmd.Data,
+		}
+		run, err := render.renderer()
+		if err != nil {
+			return err
+		}
+		for _, result := range decoded {
+			if err := run.file(result.Nodes, result.Templates); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, result := range decoded {
+			if err := write(result.Nodes); err != nil {
+				return fmt.Errorf("%s: %w", result.Path, err)
+			}
+		}
+	}
+	return nil
+}
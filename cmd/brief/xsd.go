@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"os"
+)
+
+type xsdCommand struct {
+	JSON   bool   `long:"json" description:"write a JSON Schema instead of an XML Schema"`
+	Schema string `short:"s" long:"schema" description:"schema file, instead of inferring from sample files"`
+	Output string `short:"o" long:"output" description:"output file (default stdout)"`
+	Args   struct {
+		Files []string `positional-arg-name:"sample" description:"sample brief files"`
+	} `positional-args:"true"`
+}
+
+// Execute xsd writes an XML Schema or JSON Schema for the XML or JSON output
+func (cmd *xsdCommand) Execute(args []string) error {
+	schema, err := loadOrInferSchema(cmd.Schema, cmd.Args.Files)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if cmd.JSON {
+		err = schema.WriteJSONSchema(&out)
+	} else {
+		err = schema.WriteXSD(&out)
+	}
+	if err != nil {
+		return err
+	}
+	if len(cmd.Output) == 0 {
+		_, err = os.Stdout.Write(out.Bytes())
+		return err
+	}
+	return os.WriteFile(cmd.Output, out.Bytes(), 0644)
+}
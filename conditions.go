@@ -0,0 +1,159 @@
+package brief
+
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+	"unicode"
+)
+
+// branch of an #if feature
+type branch struct {
+	active bool // the lines of this branch are decoded
+	taken  bool // a branch of this #if has been decoded, or its parent is skipped
+	inElse bool // after the #else
+	pos    scanner.Position
+}
+
+// skipping true when the lines are in a branch that is not decoded
+func (dec *Decoder) skipping() bool {
+	size := len(dec.branches)
+	return size > 0 && !dec.branches[size-1].active
+}
+
+// ifFeature starts a branch decoded when the condition is true, an unquoted
+// condition is the rest of the line
+func (dec *Decoder) ifFeature() {
+	var cond string
+	switch dec.ScanType {
+	case scanner.Ident, '!':
+		cond = dec.Token + dec.restOfLine()
+	case scanner.String, scanner.RawString:
+		dec.trimContentToken()
+		cond = dec.Token
+	default:
+		dec.Error("#if expects a name or a condition")
+		return
+	}
+	ok, err := dec.condition(cond)
+	if err != nil {
+		dec.Errorf("#if %s", err)
+		return
+	}
+	dec.branches = append(dec.branches, branch{active: ok, taken: ok, pos: dec.Text.Position})
+}
+
+// restOfLine up to the newline, a // comment to the end of the line is skipped
+func (dec *Decoder) restOfLine() string {
+	var line []rune
+	for {
+		ch := dec.Text.Peek()
+		if ch == scanner.EOF || ch == '\n' || ch == '\r' {
+			return string(line)
+		}
+		dec.Text.Next()
+		if ch == '/' && len(line) > 0 && line[len(line)-1] == '/' {
+			for ch = dec.Text.Peek(); ch != scanner.EOF && ch != '\n' && ch != '\r'; ch = dec.Text.Peek() {
+				dec.Text.Next()
+			}
+			return string(line[:len(line)-1])
+		}
+		line = append(line, ch)
+	}
+}
+
+// condition is true for a name set to a value other than "", "false" or
+// "0", a !name is the opposite, and name == value or name != value compare
+// an unset variable is "" so it is never an error
+func (dec *Decoder) condition(cond string) (bool, error) {
+	cond = strings.TrimSpace(cond)
+	not := strings.HasPrefix(cond, "!")
+	rest := strings.TrimSpace(strings.TrimPrefix(cond, "!"))
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	name, rest := rest[:end], strings.TrimSpace(rest[end:])
+	if len(name) == 0 {
+		return false, fmt.Errorf("missing name in %q", cond)
+	}
+	value, _ := dec.lookup(name)
+	if len(rest) == 0 {
+		set := value != "" && value != "false" && value != "0"
+		return set != not, nil
+	}
+	equal := strings.HasPrefix(rest, "==")
+	if not || (!equal && !strings.HasPrefix(rest, "!=")) {
+		return false, fmt.Errorf("invalid condition %q", cond)
+	}
+	want := strings.Trim(strings.TrimSpace(rest[2:]), "'\"")
+	return (value == want) == equal, nil
+}
+
+// branchFeature handles the #else and #end features which have no value
+// false for any other feature
+func (dec *Decoder) branchFeature() bool {
+	feature := strings.ToLower(dec.Feature)
+	if feature != "else" && feature != "end" {
+		return false
+	}
+	size := len(dec.branches)
+	if size == 0 {
+		dec.Errorf("#%s without #if", feature)
+		return true
+	}
+	last := &dec.branches[size-1]
+	if feature == "end" {
+		dec.branches = dec.branches[:size-1]
+		return true
+	}
+	if last.inElse {
+		dec.Error("#else after #else")
+		return true
+	}
+	last.inElse = true
+	last.active = !last.taken
+	return true
+}
+
+// skip a token of a branch that is not decoded, only the #if, #else and
+// #end features are followed so that nested branches match
+func (dec *Decoder) skip() {
+	if dec.Text.LineStart {
+		dec.State = NewLine
+	}
+	switch {
+	case dec.State == FeatureSet:
+		switch {
+		case strings.ToLower(dec.Feature) == "if":
+			// the parent is skipped so no branch is decoded
+			dec.branches = append(dec.branches, branch{taken: true, pos: dec.Text.Position})
+		case dec.ScanType == '#':
+			dec.readDelimited() // the block of a skipped #defaults
+		}
+		dec.State = KeyEmpty
+	case dec.State == OnFeature && dec.ScanType == scanner.Ident:
+		dec.Feature = dec.Token
+		dec.State = FeatureSet
+		if dec.branchFeature() {
+			dec.State = KeyEmpty
+		}
+	case dec.ScanType == '#' && dec.State == NewLine:
+		dec.State = OnFeature
+	case dec.ScanType == '#':
+		dec.readDelimited() // a content block, or a block of a skipped feature
+		dec.State = KeyEmpty
+	default:
+		dec.State = KeyEmpty
+	}
+}
+
+// checkBranches at the end of the file, every #if needs an #end
+func (dec *Decoder) checkBranches() {
+	if size := len(dec.branches); size > 0 && dec.Err == nil {
+		pos := dec.branches[size-1].pos
+		dec.Err = &DecodeError{Pos: pos, Token: "#if", Msg: "#if without #end"}
+	}
+}
@@ -0,0 +1,154 @@
+package brief_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeVars(text string, vars map[string]string) ([]*brief.Node, error) {
+	dec := brief.NewDecoder(strings.NewReader(text), 4, "")
+	dec.Vars = vars
+	return dec.Decode()
+}
+
+func TestConditions(t *testing.T) {
+	results := brief.DecodeFiles([]string{"tests/variants.brief"}, brief.DecodeOptions{
+		Vars: map[string]string{"env": "prod"},
+	})
+	require.NoError(t, results[0].Err)
+	service := results[0].Nodes[0]
+	require.Len(t, service.Body, 2)
+	assert.Equal(t, "3", service.Body[0].Name)
+	assert.Equal(t, "db.prod", service.Body[1].Keys["host"])
+
+	results = brief.DecodeFiles([]string{"tests/variants.brief"}, brief.DecodeOptions{
+		Vars: map[string]string{"env": "dev", "debug": "true"},
+	})
+	require.NoError(t, results[0].Err)
+	service = results[0].Nodes[0]
+	require.Len(t, service.Body, 3)
+	assert.Equal(t, "1", service.Body[0].Name)
+	assert.Equal(t, "localhost", service.Body[1].Keys["host"])
+	assert.Equal(t, "debug", service.Body[2].Keys["level"])
+}
+
+func TestConditionsNested(t *testing.T) {
+	text := "#if \"env != prod\"\n" +
+		"dev\n" +
+		"    #if tier\n" +
+		"    extra note:\"${undefined}\" `skipped #if`\n" +
+		"    #template skipped #|{{ .Name }}|#\n" +
+		"    #else\n" +
+		"    none\n" +
+		"    #end\n" +
+		"#else\n" +
+		"prod tier:\"${tier}\"\n" +
+		"    #if \"!tier\"\n" +
+		"    none\n" +
+		"    #end\n" +
+		"#END\n" +
+		"last\n"
+	nodes, err := decodeVars(text, map[string]string{"env": "prod", "tier": "gold"})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "prod", nodes[0].Type)
+	assert.Equal(t, "gold", nodes[0].Keys["tier"])
+	assert.Empty(t, nodes[0].Body)
+	assert.Equal(t, "last", nodes[1].Type)
+
+	nodes, err = decodeVars(text, map[string]string{"tier": "0"})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "dev", nodes[0].Type)
+	require.Len(t, nodes[0].Body, 1)
+	assert.Equal(t, "none", nodes[0].Body[0].Type)
+	_, err = decodeVars(text, map[string]string{"tier": "gold"})
+	assert.Error(t, err, "the included branch is decoded")
+}
+
+func TestConditionsStream(t *testing.T) {
+	text := "root\n#if flag\n    a\n#else\n    b\n#end\n    c\n"
+	dec := brief.NewDecoder(strings.NewReader(text), 4, "")
+	types := make([]string, 0)
+	for {
+		event, err := dec.NextEvent()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if start, ok := event.(brief.StartElement); ok {
+			types = append(types, start.Type)
+		}
+	}
+	assert.Equal(t, []string{"root", "b", "c"}, types)
+}
+
+func TestConditionsSkipBlocks(t *testing.T) {
+	text := "#if flag\n" +
+		"#defaults #|\nschema\n    element:elem\n        key:size default:|1\n|#\n" +
+		"#defaults `schema`\n" +
+		"#template help #|{{ .Name }}|#\n" +
+		"#schema \"none.schema.brief\"\n" +
+		"#define skipped \"${undefined}\"\n" +
+		"skipped #|#end|#\n" +
+		"#end\n" +
+		"elem\n"
+	dec := brief.NewDecoder(strings.NewReader(text), 4, "")
+	nodes, err := dec.Decode()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "elem", nodes[0].Type)
+	assert.Empty(t, nodes[0].Keys, "skipped #defaults are not applied")
+	assert.Nil(t, dec.Templates)
+	assert.Empty(t, dec.SchemaFile)
+}
+
+func TestConditionOperators(t *testing.T) {
+	vars := map[string]string{"op": "a!=b", "name": "x", "flag": "true"}
+	tests := []struct {
+		Cond string
+		Want bool
+	}{
+		{"op == a!=b", true},
+		{"op != a!=b", false},
+		{"op==a!=b", true},
+		{"name!=y", true},
+		{"name == 'x'", true},
+		{"!flag", false},
+		{"!unset", true},
+		{"unset == ''", true},
+	}
+	for _, test := range tests {
+		nodes, err := decodeVars("#if \""+test.Cond+"\"\nyes\n#else\nno\n#end\n", vars)
+		require.NoError(t, err, test.Cond)
+		assert.Equal(t, test.Want, nodes[0].Type == "yes", test.Cond)
+		nodes, err = decodeVars("#if "+test.Cond+" // unquoted\nyes\n#else\nno\n#end\n", vars)
+		require.NoError(t, err, test.Cond)
+		assert.Equal(t, test.Want, nodes[0].Type == "yes", test.Cond)
+	}
+	for _, cond := range []string{"name! = x", "name = x", "!name == x", "name x", "== x"} {
+		_, err := decodeVars("#if \""+cond+"\"\n#end\n", vars)
+		assert.Error(t, err, cond)
+		_, err = decodeVars("#if "+cond+"\n#end\n", vars)
+		assert.Error(t, err, cond)
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	for _, text := range []string{
+		"#if flag\nelem\n",
+		"elem\n#else\n",
+		"elem\n#end\n",
+		"#if flag\n#else\n#else\n#end\n",
+		"#if \"== x\"\n#end\n",
+		"#if 3\n#end\n",
+	} {
+		_, err := decodeVars(text, nil)
+		assert.Error(t, err, text)
+	}
+}
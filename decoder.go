@@ -1,12 +1,15 @@
 package brief
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/scanner"
+	"text/template"
 )
 
 // DecoderState constants
@@ -38,7 +41,29 @@ type Decoder struct {
 	Key, Feature   string
 	Padding        int
 	Dir            string
+	SchemaFile     string             // set by the #schema feature
+	Defaults       *Schema            // set by the #defaults feature
+	Templates      *template.Template // set by the #template feature
+	Includes       []Include          // set by the #include feature
+	Resolver       Resolver           // opens #include files, OSResolver when nil
+	Cache          *IncludeCache      // reuses decoded #include files when set
+	Vars           map[string]string  // variables for ${name}, #define adds to a copy
+	EnvFallback    bool               // undefined variables are read from the environment
+	vars           map[string]string  // variables in the scope of this file
+	Context        context.Context    // the decode ends when the context is done
+	Limits         Limits             // bounds the resources used by a decode
+	usage          *usage             // of the limits, shared with includes
+	includeDepth   int                // of this decoder in the includes
+	depth          int                // of the including element
+	streaming      bool               // events instead of nodes, see NextEvent
+	events         []Event            // events not yet returned by NextEvent
+	ready          int                // events of completed lines
+	start          *StartElement      // start event of the current element
+	branches       []branch           // open #if features
+	chain          []string           // absolute paths of the files including this one
+	flight         *flight            // of the cache, decoded by this decoder or one including it
 	Debug          bool
+	Strict         bool // decoded nodes are strict, see Node.Strict
 }
 
 // NewDecoder from reader with tabsize and optional directory
@@ -52,22 +77,23 @@ func NewDecoder(reader io.Reader, tabsize int, srcdir string) *Decoder {
 			dir = "./"
 		}
 	}
-	var decoder Decoder
-	decoder.Dir = dir
-	decoder.Text.Init(reader, tabsize)
+	decoder := &Decoder{Dir: dir}
+	decoder.Text.Init(&input{dec: decoder, reader: reader}, tabsize)
 	decoder.Roots = make([]*Node, 0)
 	decoder.Nesting = make([]*Node, 0)
-	return &decoder
+	return decoder
 }
 
 // NewFileDecoder new decoder that reads from a filename
+// the file is read into memory so there is nothing to close
 func NewFileDecoder(filename string) (*Decoder, error) {
-	file, err := os.Open(filename)
+	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-	return NewDecoder(file, 4, fileDir(filename)), nil
+	dec := NewDecoder(bytes.NewReader(data), 4, fileDir(filename))
+	dec.Text.Filename = filename
+	return dec, nil
 }
 
 func fileDir(filename string) string {
@@ -88,7 +114,9 @@ func DecodeFile(filename string) ([]*Node, error) {
 		return nil, err
 	}
 	defer file.Close()
-	nodes, err := Decode(file, fileDir(filename))
+	dec := NewDecoder(file, 4, fileDir(filename))
+	dec.Text.Filename = filename
+	nodes, err := dec.Decode()
 	if err != nil {
 		return nil, err
 	}
@@ -106,14 +134,33 @@ func (dec *Decoder) Errorf(format string, args ...interface{}) error {
 	return dec.Error(fmt.Sprintf(format, args...))
 }
 
+// DecodeError is a positioned decoder error
+// Err is the error reported before this one, if any
+type DecodeError struct {
+	Pos   scanner.Position
+	Token string
+	Msg   string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("%s on %q at %d:%d", e.Msg, e.Token, e.Pos.Line, e.Pos.Column)
+	if e.Err != nil {
+		return fmt.Sprintf("%s\n%s", e.Err, msg)
+	}
+	return msg
+}
+
+// Unwrap the previous error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
 // Error added to decoder and returned
 func (dec *Decoder) Error(msg string) error {
 	pos := dec.Text.Pos()
-	err := fmt.Errorf("%s on %q at %d:%d", msg, dec.Token, pos.Line, pos.Column-len(dec.Token))
-	if dec.Err != nil {
-		err = fmt.Errorf("%s\n%s", dec.Err, err)
-	}
-	dec.Err = err
+	pos.Column -= len(dec.Token)
+	dec.Err = &DecodeError{Pos: pos, Token: dec.Token, Msg: msg, Err: dec.Err}
 	return dec.Err
 }
 
@@ -137,7 +184,12 @@ func (dec *Decoder) parent() *Node {
 	return nil
 }
 
+// next token, none once a limit or the context has ended the decode
 func (dec *Decoder) next() bool {
+	if dec.usage != nil && dec.usage.err != nil {
+		dec.ScanType, dec.Token = scanner.EOF, ""
+		return false
+	}
 	dec.ScanType = dec.Text.Scan()
 	dec.Token = dec.Text.TokenText()
 	return dec.ScanType != scanner.EOF
@@ -149,7 +201,14 @@ func (dec *Decoder) setName() {
 		dec.Error("SetName parent not found")
 		return
 	}
-	parent.Name = strings.Trim(dec.Token, "\"")
+	name, ok := dec.unquote()
+	if !ok {
+		return
+	}
+	parent.Name = name
+	if dec.start != nil {
+		dec.start.Name = parent.Name
+	}
 }
 
 func (dec *Decoder) setValue(neg bool) {
@@ -161,11 +220,15 @@ func (dec *Decoder) setValue(neg bool) {
 	if len(dec.Key) == 0 {
 		dec.Error("SetValue no key")
 	}
-	if neg && dec.Token[0] != '"' {
-		parent.Put(dec.Key, "-"+dec.Token)
+	value, ok := dec.unquote()
+	if !ok {
 		return
 	}
-	parent.Put(dec.Key, strings.Trim(dec.Token, "\""))
+	if neg && dec.Token[0] != '"' {
+		value = "-" + dec.Token
+	}
+	parent.Put(dec.Key, value)
+	dec.emit(Key{Name: dec.Key, Value: value})
 }
 
 func (dec *Decoder) setContent() {
@@ -174,7 +237,9 @@ func (dec *Decoder) setContent() {
 		dec.Error("SetContent parent not found")
 		return
 	}
-	parent.Content = strings.Trim(dec.Token, "`")
+	parent.Content = dec.interpolateContent(strings.Trim(dec.Token, "`"))
+	dec.checkContent(parent.Content)
+	dec.emit(Content{Text: parent.Content})
 }
 
 func (dec *Decoder) findParent(indent int) *Node {
@@ -185,17 +250,26 @@ func (dec *Decoder) findParent(indent int) *Node {
 			return parent
 		}
 		dec.Nesting = dec.Nesting[:last]
+		dec.emit(EndElement{Type: parent.Type, Name: parent.Name, Depth: last})
 	}
 	return nil
 }
 
 func (dec *Decoder) addNode() {
 	node := NewNode(dec.Token, dec.indent())
+	node.Pos = dec.Text.Position
+	node.Strict = dec.Strict
 	parent := dec.findParent(node.Indent)
-	if parent != nil {
+	dec.checkNode(len(dec.Nesting))
+	switch {
+	case dec.streaming:
+		node.Parent = parent
+		dec.start = &StartElement{Type: node.Type, Depth: len(dec.Nesting), Pos: node.Pos}
+		dec.emit(dec.start)
+	case parent != nil:
 		node.Parent = parent
 		parent.Body = append(parent.Body, node)
-	} else {
+	default:
 		dec.Roots = append(dec.Roots, node)
 	}
 	dec.Nesting = append(dec.Nesting, node)
@@ -205,140 +279,186 @@ func (dec *Decoder) addNode() {
 func (dec *Decoder) Decode() ([]*Node, error) {
 	dec.State = KeyEmpty
 	for dec.next() {
-		if dec.Err != nil {
-			return nil, dec.Err
+		if err := dec.step(); err != nil {
+			return nil, err
 		}
-		if dec.Text.LineStart {
-			switch dec.State {
-			case KeyElem, KeyEmpty, OnComment:
-				dec.State = NewLine
-			default:
-				return nil, dec.Error("invalid stray token at end of line above")
-			}
+	}
+	dec.checkBranches()
+	if err := dec.failed(); err != nil {
+		return nil, err
+	}
+	if dec.Defaults != nil {
+		if err := dec.Defaults.Normalize(dec.Roots); err != nil {
+			return nil, err
 		}
-		// if this is a feature use the feature handler
-		if dec.State == FeatureSet {
-			dec.handleFeature()
-			dec.State = KeyEmpty
-			continue
+	}
+	return dec.Roots, nil
+}
+
+// step the state machine with the token just scanned
+func (dec *Decoder) step() error {
+	dec.checkContext()
+	if err := dec.failed(); err != nil {
+		return err
+	}
+	if dec.skipping() {
+		dec.skip()
+		return dec.failed()
+	}
+	if dec.Text.LineStart {
+		switch dec.State {
+		case KeyElem, KeyEmpty, OnComment:
+			dec.State = NewLine
+		default:
+			return dec.Error("invalid stray token at end of line above")
 		}
-		switch dec.ScanType {
-		case scanner.Comment: // skip comments
-			dec.State = OnComment
-		case scanner.Ident:
-			switch dec.State {
-			case NewLine:
-				dec.addNode()
-				dec.Key = dec.Token
-				dec.State = KeyElem
-			case KeyElem: // no colon after elem
-				dec.Key = dec.Token
-				dec.State = KeyValue
-			case KeyEmpty:
-				dec.Key = dec.Token
-				dec.State = KeyValue
-			case OnName:
-				dec.setName()
-				dec.Key = ""
-				dec.State = KeyEmpty
-			case NegValue:
-				return nil, dec.Error("invalid minus before symbol")
-			case OnValue:
-				dec.setValue(false)
-				dec.Key = ""
-				dec.State = KeyEmpty
-			case OnFeature:
-				dec.Feature = dec.Token
-				dec.State = FeatureSet
-			default:
-				return nil, dec.Error("invalid identifier found")
-			}
-		case scanner.String, scanner.Int, scanner.Float:
-			if dec.State == NegValue && dec.ScanType == scanner.String {
-				return nil, dec.Error("invalid minus before string")
-			}
-			switch dec.State {
-			case OnName:
-				dec.setName()
-				dec.Key = ""
-				dec.State = KeyEmpty
-			case OnValue, NegValue:
-				dec.setValue(dec.State == NegValue)
-				dec.Key = ""
-				dec.State = KeyEmpty
-			default:
-				return nil, dec.Error("invalid value found")
-			}
-		case scanner.RawString:
-			if dec.State == NegValue {
-				return nil, dec.Error("invalid minus before content")
-			}
-			switch dec.State {
-			case KeyElem, KeyEmpty:
-				dec.Key = ""
-				dec.setContent()
-				dec.State = KeyEmpty
-			default:
-				return nil, dec.Error("invalid content found")
-			}
-		case '-':
-			switch dec.State {
-			case OnValue, OnName:
-				dec.State = NegValue
-			default:
-				return nil, dec.Error("invalid minus")
-			}
-		case ':':
-			switch dec.State {
-			case KeyElem:
-				dec.State = OnName
-			case KeyValue:
-				dec.State = OnValue
-			default:
-				return nil, dec.Error("invalid syntax ':'")
-			}
-		case '+':
-			switch dec.State {
-			case NewLine:
-				dec.State = KeyEmpty
-			default:
-				return nil, dec.Error("invalid syntax '+'")
-			}
-		case '#':
-			switch dec.State {
-			case KeyElem, KeyEmpty:
-				dec.Key = ""
-				dec.readBlock()
+	}
+	// if this is a feature use the feature handler
+	if dec.State == FeatureSet {
+		dec.handleFeature()
+		dec.State = KeyEmpty
+		return nil
+	}
+	switch dec.ScanType {
+	case scanner.Comment: // skip comments
+		dec.State = OnComment
+	case scanner.Ident:
+		switch dec.State {
+		case NewLine:
+			dec.addNode()
+			dec.Key = dec.Token
+			dec.State = KeyElem
+		case KeyElem: // no colon after elem
+			dec.Key = dec.Token
+			dec.State = KeyValue
+		case KeyEmpty:
+			dec.Key = dec.Token
+			dec.State = KeyValue
+		case OnName:
+			dec.setName()
+			dec.Key = ""
+			dec.State = KeyEmpty
+		case NegValue:
+			return dec.Error("invalid minus before symbol")
+		case OnValue:
+			dec.setValue(false)
+			dec.Key = ""
+			dec.State = KeyEmpty
+		case OnFeature:
+			dec.Feature = dec.Token
+			dec.State = FeatureSet
+			if dec.branchFeature() {
 				dec.State = KeyEmpty
-			case NewLine:
-				dec.State = OnFeature
-			default:
-				return nil, dec.Error("invalid syntax '#'")
 			}
+		default:
+			return dec.Error("invalid identifier found")
+		}
+	case scanner.String, scanner.Int, scanner.Float:
+		if dec.State == NegValue && dec.ScanType == scanner.String {
+			return dec.Error("invalid minus before string")
+		}
+		switch dec.State {
+		case OnName:
+			dec.setName()
+			dec.Key = ""
+			dec.State = KeyEmpty
+		case OnValue, NegValue:
+			dec.setValue(dec.State == NegValue)
+			dec.Key = ""
+			dec.State = KeyEmpty
+		default:
+			return dec.Error("invalid value found")
+		}
+	case scanner.RawString:
+		if dec.State == NegValue {
+			return dec.Error("invalid minus before content")
+		}
+		switch dec.State {
+		case KeyElem, KeyEmpty:
+			dec.Key = ""
+			dec.setContent()
+			dec.State = KeyEmpty
+		default:
+			return dec.Error("invalid content found")
+		}
+	case '-':
+		switch dec.State {
+		case OnValue, OnName:
+			dec.State = NegValue
+		default:
+			return dec.Error("invalid minus")
+		}
+	case ':':
+		switch dec.State {
+		case KeyElem:
+			dec.State = OnName
+		case KeyValue:
+			dec.State = OnValue
+		default:
+			return dec.Error("invalid syntax ':'")
+		}
+	case '+':
+		switch dec.State {
+		case NewLine:
+			dec.State = KeyEmpty
+		default:
+			return dec.Error("invalid syntax '+'")
+		}
+	case '#':
+		switch dec.State {
+		case KeyElem, KeyEmpty:
+			dec.Key = ""
+			dec.readBlock()
+			dec.State = KeyEmpty
+		case NewLine:
+			dec.State = OnFeature
+		default:
+			return dec.Error("invalid syntax '#'")
 		}
 	}
-	return dec.Roots, nil
+	return nil
 }
 
 func (dec *Decoder) readBlock() error {
+	text, err := dec.readDelimited()
+	if err != nil {
+		return err
+	}
+	dec.Token = text
+	dec.setContent()
+	return nil
+}
+
+// readDelimited reads a #| |# block after the '#' and returns the text inside
+// MaxContentBytes and the context are checked while the block is read, so
+// an unterminated block ends the decode as soon as a limit is reached
+func (dec *Decoder) readDelimited() (string, error) {
+	pos := dec.Text.Position
 	delim := dec.Text.Next()
 	if !strings.ContainsAny(string(delim), "|@$%") {
-		return dec.Error("invalid block delimiter: #" + string(delim))
+		return "", dec.Error("invalid block delimiter: #" + string(delim))
 	}
+	max := dec.Limits.MaxContentBytes
 	var build strings.Builder
-	for ch := dec.Text.Next(); ch != scanner.EOF; ch = dec.Text.Next() {
+	for ch, read := dec.Text.Next(), 1; ch != scanner.EOF; ch, read = dec.Text.Next(), read+1 {
+		if read%4096 == 0 {
+			if dec.checkContext(); dec.failed() != nil {
+				return "", dec.failed()
+			}
+		}
 		if ch == delim {
 			at := dec.Text.Next()
 			if at == '#' {
-				dec.Token = build.String()
-				dec.setContent()
-				return nil
+				return build.String(), nil
 			}
 			build.WriteRune(ch)
-			build.WriteRune(at)
-			continue
+			ch = at
 		}
 		build.WriteRune(ch)
+		if max > 0 && build.Len() > max {
+			dec.fail(&LimitError{Limit: "MaxContentBytes", Max: max, Pos: pos})
+			return "", dec.Err
+		}
 	}
-	return dec.Error("Found EOF while reading block no matching " + string(delim))
+	return "", dec.Error("Found EOF while reading block no matching " + string(delim))
 }
@@ -2,7 +2,9 @@ package brief_test
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -206,3 +208,21 @@ func TestScannerCases(t *testing.T) {
 		}
 	}
 }
+
+func TestSchemaFeature(t *testing.T) {
+	dec, err := brief.NewFileDecoder("tests/linked.brief")
+	require.NoError(t, err)
+	nodes, err := dec.Decode()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "cli.schema.brief", filepath.Base(dec.SchemaFile))
+	assert.True(t, filepath.IsAbs(dec.SchemaFile))
+}
+
+func TestTrailingFeatureError(t *testing.T) {
+	_, err := brief.Decode(strings.NewReader("pages\n    #include \"no_such_file.brief\""), "tests")
+	assert.Error(t, err, "error in last feature was lost")
+	var derr *brief.DecodeError
+	require.True(t, errors.As(err, &derr))
+	assert.Equal(t, 2, derr.Pos.Line)
+}
@@ -9,14 +9,10 @@ import (
 // Encode converts a node into brief format
 func (node *Node) Encode() []byte {
 	var out strings.Builder
-	body := node.write(&out)
-	for len(body) > 0 {
-		next := body[0]
-		body = body[1:]
-		more := next.write(&out)
-		body = append(more, body...)
-	}
-
+	node.Walk(func(n *Node, depth int) WalkAction {
+		n.write(&out)
+		return Continue
+	})
 	return []byte(out.String())
 }
 
@@ -49,7 +45,7 @@ func NoQuote(value string) bool {
 
 }
 
-func (node *Node) write(out *strings.Builder) []*Node {
+func (node *Node) write(out *strings.Builder) {
 	indent := strings.Repeat(" ", node.Indent)
 	out.WriteString(indent + node.Type)
 	if len(node.Name) > 0 {
@@ -59,7 +55,8 @@ func (node *Node) write(out *strings.Builder) []*Node {
 			out.WriteString(fmt.Sprintf(":%q", node.Name))
 		}
 	}
-	for key, val := range node.Keys {
+	for _, key := range sortedKeys(node.Keys) {
+		val := node.Keys[key]
 		if NoQuote(val) {
 			out.WriteString(fmt.Sprintf(" %s:%s", key, val))
 			continue
@@ -70,5 +67,4 @@ func (node *Node) write(out *strings.Builder) []*Node {
 		out.WriteString(fmt.Sprintf(" `%s`", node.Content))
 	}
 	out.WriteString("\n")
-	return node.Body
 }
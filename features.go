@@ -2,10 +2,11 @@ package brief
 
 import (
 	"fmt"
-	"os"
+	"io"
 	"path/filepath"
 	"strings"
 	"text/scanner"
+	"text/template"
 )
 
 func (dec *Decoder) handleFeature() {
@@ -17,6 +18,45 @@ func (dec *Decoder) handleFeature() {
 			dec.trimContentToken()
 			dec.includeFile(dec.Token)
 		}
+	case "schema":
+		switch dec.ScanType {
+		case scanner.String, scanner.RawString:
+			dec.trimContentToken()
+			dec.schemaFile(dec.Token)
+		default:
+			dec.Error("#schema expects a file name")
+		}
+	case "defaults":
+		switch dec.ScanType {
+		case scanner.RawString:
+			dec.trimContentToken()
+			dec.defaults(dec.Token, dec.Text.Position.Line)
+		case '#':
+			line := dec.Text.Position.Line
+			block, err := dec.readDelimited()
+			if err == nil {
+				dec.defaults(block, line)
+			}
+		default:
+			dec.Error("#defaults expects a content block")
+		}
+	case "define", "set":
+		switch dec.ScanType {
+		case scanner.Ident:
+			dec.define(dec.Token)
+		default:
+			dec.Errorf("#%s expects a name", dec.Feature)
+		}
+	case "if":
+		dec.ifFeature()
+	case "template":
+		switch dec.ScanType {
+		case scanner.Ident, scanner.String, scanner.RawString:
+			dec.trimContentToken()
+			dec.partial(dec.Token)
+		default:
+			dec.Error("#template expects a name")
+		}
 	default:
 		dec.Errorf("unknown brief feature %s", dec.Feature)
 	}
@@ -31,28 +71,49 @@ func (dec *Decoder) trimContentToken() {
 	}
 }
 
+// Include records an #include feature, including those of included files
+type Include struct {
+	Pos  scanner.Position // position of the file name in the including file
+	Path string           // path of the included file
+}
+
 func (dec *Decoder) includeFile(filename string) {
 	if !filepath.IsAbs(filename) {
 		filename = filepath.Join(dec.Dir, filename)
 	}
+	dec.Includes = append(dec.Includes, Include{Pos: dec.Text.Position, Path: filename})
+	if !dec.checkInclude(filename) {
+		return
+	}
+	if dec.including(filename) {
+		dec.Errorf("#include %s includes itself", filename)
+		return
+	}
 	if dec.Debug {
 		fmt.Println("*** include", filename)
 	}
-	file, err := os.Open(filename)
+	var inc *included
+	var err error
+	if dec.Cache != nil && dec.Limits.none() && len(dec.scope()) == 0 && !dec.EnvFallback {
+		inc, err = dec.Cache.include(dec, filename, dec.indent())
+		if err == nil {
+			for _, node := range inc.nodes {
+				node.SetStrict(dec.Strict)
+			}
+		}
+	} else {
+		inc, err = dec.decodeInclude(filename, dec.indent(), dec.flight)
+	}
+	dec.Includes = append(dec.Includes, inc.includes...)
 	if err != nil {
 		dec.Error(err.Error())
 		return
 	}
-	defer file.Close()
-	dir := filepath.Dir(filename)
-	idec := NewDecoder(file, dec.Text.TabCount, dir)
-	idec.Debug = dec.Debug
-	idec.Padding = dec.indent()
-	nodes, err := idec.Decode()
-	if err != nil {
+	if err := dec.addTemplates(inc.templates); err != nil {
 		dec.Error(err.Error())
 		return
 	}
+	nodes := inc.nodes
 	size := len(nodes)
 	if size == 0 {
 		if dec.Debug {
@@ -61,9 +122,186 @@ func (dec *Decoder) includeFile(filename string) {
 		return
 	}
 	parent := dec.findParent(nodes[size-1].Indent)
+	if dec.streaming {
+		for _, node := range nodes {
+			dec.emitNode(node, len(dec.Nesting))
+		}
+		return
+	}
 	if parent != nil {
+		for _, node := range nodes {
+			node.Parent = parent
+		}
 		parent.Body = append(parent.Body, nodes...)
 		return
 	}
 	dec.Roots = append(dec.Roots, nodes...)
 }
+
+// including true when the file is being decoded by this decoder or one that
+// includes it, so including it again would never end
+func (dec *Decoder) including(filename string) bool {
+	path := absPath(filename)
+	if len(dec.Text.Filename) > 0 && absPath(dec.Text.Filename) == path {
+		return true
+	}
+	for _, open := range dec.chain {
+		if open == path {
+			return true
+		}
+	}
+	return false
+}
+
+// included file decoded by an #include
+type included struct {
+	nodes     []*Node
+	templates *template.Template
+	includes  []Include
+}
+
+// decodeInclude decodes an included file with the indent padding
+// owner is the cache flight of the decode, if any
+func (dec *Decoder) decodeInclude(filename string, padding int, owner *flight) (*included, error) {
+	resolver, err := dec.resolver()
+	if err != nil {
+		return &included{}, err
+	}
+	file, err := resolver.Open(filename)
+	if err != nil {
+		return &included{}, err
+	}
+	defer file.Close()
+	idec := dec.nested(file, filepath.Dir(filename))
+	idec.Text.Filename = filename
+	idec.Padding = padding
+	idec.chain = append(idec.chain, absPath(filename))
+	idec.flight = owner
+	dec.limitInclude(idec)
+	nodes, err := idec.Decode()
+	return &included{nodes: nodes, templates: idec.Templates, includes: idec.Includes}, err
+}
+
+// nested decoder of an included file or a #defaults block, it shares the
+// resolver, cache, variables, limits and include chain of this decoder
+func (dec *Decoder) nested(reader io.Reader, dir string) *Decoder {
+	ndec := NewDecoder(reader, dec.Text.TabCount, dir)
+	ndec.Debug = dec.Debug
+	ndec.Resolver = dec.Resolver
+	ndec.Cache = dec.Cache
+	ndec.Strict = dec.Strict
+	ndec.Vars = dec.scope()
+	ndec.EnvFallback = dec.EnvFallback
+	ndec.Context = dec.Context
+	ndec.Limits = dec.Limits
+	ndec.usage = dec.shared()
+	ndec.includeDepth = dec.includeDepth
+	ndec.flight = dec.flight
+	ndec.chain = dec.chain[:len(dec.chain):len(dec.chain)]
+	if len(dec.Text.Filename) > 0 {
+		ndec.chain = append(ndec.chain, absPath(dec.Text.Filename))
+	}
+	return ndec
+}
+
+// schemaFile links the decoded file to a schema file
+func (dec *Decoder) schemaFile(filename string) {
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(dec.Dir, filename)
+	}
+	if len(dec.SchemaFile) > 0 && dec.SchemaFile != filename {
+		dec.Errorf("#schema already set to %s", dec.SchemaFile)
+		return
+	}
+	dec.SchemaFile = filename
+}
+
+// defaults adds the schema in block, which starts on line, to the decoder
+// defaults.  The block is decoded like the rest of the file, so its includes
+// are resolved, limited and recorded the same way.
+func (dec *Decoder) defaults(block string, line int) {
+	if _, err := dec.resolver(); err != nil {
+		dec.Error(err.Error())
+		return
+	}
+	ddec := dec.nested(strings.NewReader(block), dec.Dir)
+	ddec.Text.Filename = dec.Text.Filename
+	nodes, err := ddec.Decode()
+	for _, inc := range ddec.Includes {
+		if inc.Pos.Filename == dec.Text.Filename {
+			inc.Pos.Line += line - 1
+		}
+		dec.Includes = append(dec.Includes, inc)
+	}
+	if err != nil {
+		dec.Errorf("#defaults %s", err)
+		return
+	}
+	if dec.Defaults == nil {
+		dec.Defaults = &Schema{}
+	}
+	if err := dec.Defaults.parse(nodes); err != nil {
+		dec.Errorf("#defaults %s", err)
+	}
+}
+
+// partial reads the content block after the name as a text/template
+func (dec *Decoder) partial(name string) {
+	dec.next()
+	var text string
+	switch dec.ScanType {
+	case scanner.RawString:
+		dec.trimContentToken()
+		text = dec.Token
+	case '#':
+		block, err := dec.readDelimited()
+		if err != nil {
+			return
+		}
+		text = block
+	default:
+		dec.Errorf("#template %s expects a content block", name)
+		return
+	}
+	if dec.Templates == nil {
+		dec.Templates = newTemplates()
+	}
+	if _, err := dec.Templates.New(name).Parse(text); err != nil {
+		dec.Errorf("#template %s", err)
+	}
+}
+
+// addTemplates from an included file
+func (dec *Decoder) addTemplates(set *template.Template) error {
+	if set == nil {
+		return nil
+	}
+	if dec.Templates == nil {
+		dec.Templates = newTemplates()
+	}
+	return addTemplates(dec.Templates, set)
+}
+
+// newTemplates set for #template partials
+// data is a stand-in for the Renderer data function which is used when the
+// partials are executed by a Renderer
+func newTemplates() *template.Template {
+	return template.New("").Funcs(FuncMap()).Funcs(template.FuncMap{
+		"data": func(key string) (string, error) {
+			return "", fmt.Errorf("no data %s", key)
+		},
+	})
+}
+
+// addTemplates every template defined in set to the templates in to
+func addTemplates(to, set *template.Template) error {
+	for _, tmpl := range set.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+		if _, err := to.AddParseTree(tmpl.Name(), tmpl.Tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
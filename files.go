@@ -0,0 +1,92 @@
+package brief
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"text/template"
+)
+
+// DecodeOptions for DecodeFiles
+type DecodeOptions struct {
+	Workers  int             // files decoded at once, the number of CPUs when zero
+	Context  context.Context // files not yet decoded fail when it is done
+	Limits   Limits          // for each file and its includes
+	Resolver Resolver        // shared by the workers so it must be safe for concurrent use
+	Cache    *IncludeCache   // shared by the workers
+	Strict   bool            // decoded nodes are strict, see Node.Strict
+	Debug    bool            // trace each decoder, the traces interleave unless Workers is 1
+	// Vars and EnvFallback for ${name} variables, see Decoder
+	Vars        map[string]string
+	EnvFallback bool
+}
+
+// FileResult of decoding one file, Err is set when it failed
+type FileResult struct {
+	Path       string
+	Nodes      []*Node
+	Includes   []Include          // the included files, as for Decoder.Includes
+	SchemaFile string             // set by the #schema feature
+	Templates  *template.Template // set by the #template feature
+	Err        error
+}
+
+// DecodeFiles decodes the files concurrently with a pool of workers
+// the results are in the order of the paths, one failed file does not stop
+// the others.  The includes of a file are decoded in order by its worker,
+// a shared Cache decodes an include used by many files once.
+func DecodeFiles(paths []string, opts DecodeOptions) []FileResult {
+	results := make([]FileResult, len(paths))
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for at := range jobs {
+				results[at] = decodeOne(paths[at], opts)
+			}
+		}()
+	}
+	for at := range paths {
+		jobs <- at
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func decodeOne(path string, opts DecodeOptions) FileResult {
+	result := FileResult{Path: path}
+	if opts.Context != nil {
+		if err := opts.Context.Err(); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	dec, err := NewFileDecoder(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	dec.Context = opts.Context
+	dec.Limits = opts.Limits
+	dec.Resolver = opts.Resolver
+	dec.Cache = opts.Cache
+	dec.Vars = opts.Vars
+	dec.EnvFallback = opts.EnvFallback
+	dec.Strict = opts.Strict
+	dec.Debug = opts.Debug
+	result.Nodes, result.Err = dec.Decode()
+	result.Includes = dec.Includes
+	result.SchemaFile = dec.SchemaFile
+	result.Templates = dec.Templates
+	return result
+}
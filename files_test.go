@@ -0,0 +1,64 @@
+package brief_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeFiles(t *testing.T) {
+	dir := t.TempDir()
+	html, err := os.ReadFile("tests/test0.brief")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "html.brief"), html, 0644))
+	paths := make([]string, 0)
+	for i := 0; i < 40; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("spec%d.brief", i))
+		text := fmt.Sprintf("spec:s%d\n    #include \"html.brief\"\n", i)
+		if i%10 == 3 {
+			text = "spec::bad\n"
+		}
+		require.NoError(t, os.WriteFile(path, []byte(text), 0644))
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(dir, "missing.brief"))
+
+	for _, workers := range []int{0, 1, 7} {
+		results := brief.DecodeFiles(paths, brief.DecodeOptions{Workers: workers})
+		require.Len(t, results, len(paths))
+		for i, result := range results {
+			assert.Equal(t, paths[i], result.Path)
+			if i%10 == 3 || i == 40 {
+				assert.Error(t, result.Err, result.Path)
+				continue
+			}
+			require.NoError(t, result.Err, result.Path)
+			require.Len(t, result.Nodes, 1)
+			assert.Equal(t, fmt.Sprintf("s%d", i), result.Nodes[0].Name)
+			assert.Equal(t, "html", result.Nodes[0].Body[0].Type)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, result := range brief.DecodeFiles(paths, brief.DecodeOptions{Context: ctx}) {
+		assert.True(t, errors.Is(result.Err, context.Canceled), result.Path)
+	}
+
+	results := brief.DecodeFiles(paths[:2], brief.DecodeOptions{Limits: brief.Limits{MaxNodes: 2}})
+	var limit *brief.LimitError
+	assert.True(t, errors.As(results[0].Err, &limit))
+}
+
+func wd(t *testing.T) string {
+	dir, err := os.Getwd()
+	require.NoError(t, err)
+	return dir
+}
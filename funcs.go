@@ -0,0 +1,233 @@
+package brief
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// FuncMap of template functions for brief nodes
+//
+//	camel pascal snake kebab upper lower   change the case of words
+//	plural                                 plural of an English noun
+//	indent nindent                         indent lines, nindent adds a newline first
+//	quote json                             quote for Go or as JSON
+//	default                                default value when a value is empty
+//	sortBy groupBy filter                  sort, group and filter nodes by a field
+//	find findAll child lookup              query nodes, see the Node methods
+//	key join                               key values and lookups of a node
+//
+// lookup fails with the error of LookupErr.  key and join return a ValueSpec
+// state like the Node methods, or fail when the node is Strict.
+//
+// Node fields used by sortBy and groupBy are key names, or @name, @type and @content.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"camel":   Camel,
+		"pascal":  Pascal,
+		"snake":   Snake,
+		"kebab":   Kebab,
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"plural":  Plural,
+		"indent":  Indent,
+		"nindent": func(n int, text string) string { return "\n" + Indent(n, text) },
+		"quote":   strconv.Quote,
+		"json":    jsonString,
+		"default": defaultValue,
+		"sortBy":  SortBy,
+		"groupBy": GroupBy,
+		"filter":  Filter,
+		"find":    func(name string, node *Node) *Node { return node.Find(name) },
+		"findAll": func(name string, node *Node) []*Node { return node.FindAll(name) },
+		"child":   func(node *Node, path ...string) *Node { return node.Child(path...) },
+		"lookup":  func(spec string, node *Node) (string, error) { return node.LookupErr(spec) },
+		"key":     keyValue,
+		"join":    joinValues,
+	}
+}
+
+// words splits an identifier on punctuation, spaces and case changes
+// such as goFlags, go_flags and HTTPServer
+func words(text string) []string {
+	result := make([]string, 0)
+	runes := []rune(text)
+	start := -1
+	for i, ch := range runes {
+		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
+			if start >= 0 {
+				result = append(result, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		prev := runes[i-1]
+		lowerNext := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsUpper(ch) && (unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && lowerNext)) {
+			result = append(result, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		result = append(result, string(runes[start:]))
+	}
+	return result
+}
+
+// capitalize the first letter of a word
+func capitalize(word string) string {
+	runes := []rune(word)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// Pascal case of text, go_flags to GoFlags
+func Pascal(text string) string {
+	var out strings.Builder
+	for _, word := range words(text) {
+		out.WriteString(capitalize(word))
+	}
+	return out.String()
+}
+
+// Camel case of text, go_flags to goFlags
+func Camel(text string) string {
+	var out strings.Builder
+	for i, word := range words(text) {
+		if i == 0 {
+			out.WriteString(strings.ToLower(word))
+			continue
+		}
+		out.WriteString(capitalize(word))
+	}
+	return out.String()
+}
+
+// Snake case of text, goFlags to go_flags
+func Snake(text string) string {
+	return strings.ToLower(strings.Join(words(text), "_"))
+}
+
+// Kebab case of text, goFlags to go-flags
+func Kebab(text string) string {
+	return strings.ToLower(strings.Join(words(text), "-"))
+}
+
+// Plural of an English noun
+func Plural(noun string) string {
+	lower := strings.ToLower(noun)
+	switch {
+	case len(noun) == 0:
+		return noun
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return noun + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return noun[:len(noun)-1] + "ies"
+	}
+	return noun + "s"
+}
+
+// Indent each line of text by n spaces
+func Indent(n int, text string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if len(line) > 0 {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// jsonString of a value
+func jsonString(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// defaultValue returns value or else dflt when value is empty
+//
+//	{{ .Keys.port | default "8080" }}
+func defaultValue(dflt string, value ...interface{}) string {
+	if len(value) == 0 || value[0] == nil {
+		return dflt
+	}
+	text := fmt.Sprint(value[0])
+	if len(text) == 0 {
+		return dflt
+	}
+	return text
+}
+
+// nodeField is a key value or @name, @type or @content of a node
+func nodeField(node *Node, field string) string {
+	switch field {
+	case NameField:
+		return node.Name
+	case "@type":
+		return node.Type
+	case ContentField:
+		return node.Content
+	}
+	return node.Keys[field]
+}
+
+// SortBy returns the nodes sorted by a field, the nodes are not changed
+func SortBy(field string, nodes []*Node) []*Node {
+	sorted := make([]*Node, len(nodes))
+	copy(sorted, nodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return nodeField(sorted[i], field) < nodeField(sorted[j], field)
+	})
+	return sorted
+}
+
+// GroupBy returns the nodes grouped by the value of a field
+//
+//	{{ range $type, $nodes := groupBy "@type" .Body }}
+func GroupBy(field string, nodes []*Node) map[string][]*Node {
+	groups := map[string][]*Node{}
+	for _, node := range nodes {
+		val := nodeField(node, field)
+		groups[val] = append(groups[val], node)
+	}
+	return groups
+}
+
+// Filter returns the nodes that match a node spec
+func Filter(name string, nodes []*Node) []*Node {
+	spec := NewSpec(name)
+	found := make([]*Node, 0)
+	for _, node := range nodes {
+		if spec.Match(node) {
+			found = append(found, node)
+		}
+	}
+	return found
+}
+
+// keyValue of a node, an error when the node is strict and the key is missing
+func keyValue(name string, node *Node) (string, error) {
+	if node.Strict {
+		return node.KeyErr(name)
+	}
+	return node.Key(name), nil
+}
+
+// joinValues of the specs looked up from node with sep
+func joinValues(sep string, node *Node, specs ...string) (string, error) {
+	found, err := node.lookups(specs)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(found, sep), nil
+}
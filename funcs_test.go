@@ -0,0 +1,67 @@
+package brief_test
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCase(t *testing.T) {
+	tests := []struct {
+		In, Camel, Pascal, Snake, Kebab string
+	}{
+		{"go_flags", "goFlags", "GoFlags", "go_flags", "go-flags"},
+		{"go-flags", "goFlags", "GoFlags", "go_flags", "go-flags"},
+		{"goFlags", "goFlags", "GoFlags", "go_flags", "go-flags"},
+		{"HTTPServer", "httpServer", "HTTPServer", "http_server", "http-server"},
+		{"project id", "projectId", "ProjectId", "project_id", "project-id"},
+		{"v2Name", "v2Name", "V2Name", "v2_name", "v2-name"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Camel, brief.Camel(test.In), test.In)
+		assert.Equal(t, test.Pascal, brief.Pascal(test.In), test.In)
+		assert.Equal(t, test.Snake, brief.Snake(test.In), test.In)
+		assert.Equal(t, test.Kebab, brief.Kebab(test.In), test.In)
+	}
+}
+
+func TestPlural(t *testing.T) {
+	for noun, plural := range map[string]string{
+		"command": "commands",
+		"class":   "classes",
+		"box":     "boxes",
+		"branch":  "branches",
+		"entry":   "entries",
+		"key":     "keys",
+	} {
+		assert.Equal(t, plural, brief.Plural(noun))
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	nodes, err := brief.DecodeFile("tests/cli.brief")
+	require.NoError(t, err)
+	tests := []struct {
+		Template, Result string
+	}{
+		{`{{ pascal .Name }} {{ .Type | plural | upper }}`, "Tool CLIS"},
+		{`{{ .Keys.missing | default "none" }}`, "none"},
+		{`{{ quote .Name }} {{ json .Keys }}`, `"tool" {"version":"1.2"}`},
+		{`{{ range sortBy "@name" (filter "command" .Body) }}{{ .Name }} {{ end }}`, "list run "},
+		{`{{ range $type, $nodes := groupBy "@type" .Body }}{{ $type }}={{ len $nodes }} {{ end }}`, "command=2 "},
+		{`{{ with find "command:run" . }}{{ len (findAll "flag" .) }}{{ end }}`, "2"},
+		{`{{ with child . "command:run" }}{{ lookup "cli" . }}{{ end }}`, "tool"},
+		{`a{{ "x\ny" | nindent 2 }}`, "a\n  x\n  y"},
+	}
+	for _, test := range tests {
+		tmpl, err := template.New("test").Funcs(brief.FuncMap()).Parse(test.Template)
+		require.NoError(t, err, test.Template)
+		var out strings.Builder
+		require.NoError(t, tmpl.Execute(&out, nodes[0]), test.Template)
+		assert.Equal(t, test.Result, out.String(), test.Template)
+	}
+}
@@ -0,0 +1,210 @@
+package brief
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// GeneratedRecord is the file in the output directory listing the generated files
+const GeneratedRecord = ".brief-generated"
+
+// Manifest maps the nodes of a spec to generated files
+// A manifest is written in brief:
+//
+//	manifest templates:tmpl
+//	    output path:"cmd/{{.Name}}.go" template:cmd.tmpl each:command
+//	    output path:"main.go" template:main.tmpl
+//
+// Each output renders the template once for every node matching the
+// each node spec, or once for each root when each is not given.
+type Manifest struct {
+	Templates string // directory of templates
+	Outputs   []*Output
+}
+
+// Output rule of a manifest
+type Output struct {
+	Path     string // template of the output file path
+	Template string // name of the template to render
+	Each     string // node spec of the nodes to render
+	Node     *Node  // source of the output rule
+}
+
+// Generated files reported by Generate
+type Generated struct {
+	Written, Unchanged, Removed []string
+}
+
+// LoadManifest from a brief file
+// the templates directory is relative to the manifest file
+func LoadManifest(filename string) (*Manifest, error) {
+	nodes, err := DecodeFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(nodes, fileDir(filename))
+}
+
+// ParseManifest from decoded nodes, dir is used for a relative templates directory
+func ParseManifest(nodes []*Node, dir string) (*Manifest, error) {
+	if len(nodes) != 1 || nodes[0].Type != "manifest" {
+		return nil, fmt.Errorf("expected a single manifest element")
+	}
+	root := nodes[0]
+	m := &Manifest{Templates: root.Keys["templates"]}
+	if len(m.Templates) == 0 {
+		m.Templates = "."
+	}
+	if !filepath.IsAbs(m.Templates) {
+		m.Templates = filepath.Join(dir, m.Templates)
+	}
+	for _, node := range root.Body {
+		if node.Type != "output" {
+			return nil, schemaError(node, "manifest expects output not %s", node.Type)
+		}
+		out := &Output{
+			Path:     node.Keys["path"],
+			Template: node.Keys["template"],
+			Each:     node.Keys["each"],
+			Node:     node,
+		}
+		if len(out.Path) == 0 || len(out.Template) == 0 {
+			return nil, schemaError(node, "output requires path and template keys")
+		}
+		m.Outputs = append(m.Outputs, out)
+	}
+	return m, nil
+}
+
+// nodes matched by the output each spec
+func (out *Output) nodes(roots []*Node) []*Node {
+	if len(out.Each) == 0 {
+		return roots
+	}
+	spec := NewSpec(out.Each)
+	found := make([]*Node, 0)
+	Walk(roots, func(node *Node, depth int) WalkAction {
+		if spec.Match(node) {
+			found = append(found, node)
+		}
+		return Continue
+	})
+	return found
+}
+
+// Generate the manifest outputs for the roots into dir using the renderer
+// Files whose content is unchanged are not written.  Files listed in the
+// GeneratedRecord of a previous run that are no longer generated are removed.
+// With dryRun nothing is written or removed.
+func (m *Manifest) Generate(r *Renderer, roots []*Node, dir string, dryRun bool) (*Generated, error) {
+	return m.GenerateFiles(r, []FileResult{{Nodes: roots}}, dir, dryRun)
+}
+
+// GenerateFiles is Generate for the roots of decoded files, the roots of
+// each file are rendered with its #template partials, see WithTemplates
+func (m *Manifest) GenerateFiles(r *Renderer, results []FileResult, dir string, dryRun bool) (*Generated, error) {
+	renderers := make([]*Renderer, len(results))
+	for i, result := range results {
+		fr, err := r.WithTemplates(result.Templates)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", result.Path, err)
+		}
+		renderers[i] = fr
+	}
+	files := map[string][]byte{}
+	for _, out := range m.Outputs {
+		// parsed for each call, so the data function is the one of r
+		path, err := template.New("path").Funcs(FuncMap()).Funcs(r.funcs()).Parse(out.Path)
+		if err != nil {
+			return nil, schemaError(out.Node, "path %s", err)
+		}
+		for i, result := range results {
+			if err := out.generate(renderers[i], path, result.Nodes, files); err != nil {
+				return nil, err
+			}
+		}
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gen := &Generated{}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		old, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(old, files[name]) {
+			gen.Unchanged = append(gen.Unchanged, name)
+			continue
+		}
+		gen.Written = append(gen.Written, name)
+		if dryRun {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, files[name], 0644); err != nil {
+			return nil, err
+		}
+	}
+	record := filepath.Join(dir, GeneratedRecord)
+	if old, err := os.ReadFile(record); err == nil {
+		for _, name := range strings.Split(string(old), "\n") {
+			if _, ok := files[name]; ok || !LocalPath(name) {
+				continue
+			}
+			gen.Removed = append(gen.Removed, name)
+			if dryRun {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	if dryRun {
+		return gen, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	list := strings.Join(names, "\n") + "\n"
+	return gen, os.WriteFile(record, []byte(list), 0644)
+}
+
+// generate the files of the output for the roots of one file
+func (out *Output) generate(r *Renderer, path *template.Template, roots []*Node, files map[string][]byte) error {
+	for _, node := range out.nodes(roots) {
+		var name strings.Builder
+		if err := path.Execute(&name, node); err != nil {
+			return schemaError(out.Node, "path %s", err)
+		}
+		local := filepath.Clean(name.String())
+		if !LocalPath(local) {
+			return schemaError(out.Node, "path %s is outside the output directory", local)
+		}
+		if _, dup := files[local]; dup {
+			return schemaError(out.Node, "path %s is generated twice", local)
+		}
+		var buf bytes.Buffer
+		if err := r.RenderTemplate(&buf, out.Template, node); err != nil {
+			return err
+		}
+		files[local] = buf.Bytes()
+	}
+	return nil
+}
+
+// LocalPath true if name is a relative path inside its directory
+func LocalPath(name string) bool {
+	name = filepath.Clean(name)
+	return len(name) > 0 && name != "." && !filepath.IsAbs(name) &&
+		name != ".." && !strings.HasPrefix(name, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,122 @@
+package brief_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	m, err := brief.LoadManifest("tests/gen/manifest.brief")
+	require.NoError(t, err)
+	require.Len(t, m.Outputs, 2)
+	r := brief.NewRenderer()
+	require.NoError(t, r.ParseDir(m.Templates))
+	r.Data["version"] = "v1"
+
+	nodes, err := brief.DecodeFile("tests/cli.brief")
+	require.NoError(t, err)
+	dir := t.TempDir()
+
+	gen, err := m.Generate(r, nodes, dir, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cmd/list.txt", "cmd/run.txt", "tool.txt"}, gen.Written)
+	_, err = os.Stat(filepath.Join(dir, "tool.txt"))
+	assert.True(t, os.IsNotExist(err), "dry run wrote a file")
+
+	gen, err = m.Generate(r, nodes, dir, false)
+	require.NoError(t, err)
+	assert.Len(t, gen.Written, 3)
+	data, err := os.ReadFile(filepath.Join(dir, "cmd", "run.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "run v1\n", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "tool.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "tool: run list\n", string(data))
+
+	// unchanged outputs are not written again
+	gen, err = m.Generate(r, nodes, dir, false)
+	require.NoError(t, err)
+	assert.Empty(t, gen.Written)
+	assert.Len(t, gen.Unchanged, 3)
+
+	// stale outputs are removed
+	nodes[0].Body = nodes[0].Body[:1]
+	gen, err = m.Generate(r, nodes, dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tool.txt"}, gen.Written)
+	assert.Equal(t, []string{"cmd/list.txt"}, gen.Removed)
+	_, err = os.Stat(filepath.Join(dir, "cmd", "list.txt"))
+	assert.True(t, os.IsNotExist(err), "stale file not removed")
+}
+
+func TestGenerateBadPath(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader("manifest\n    output path:\"../{{.Name}}\" template:main"), "tests")
+	require.NoError(t, err)
+	m, err := brief.ParseManifest(nodes, "tests/gen/tmpl")
+	require.NoError(t, err)
+	r := brief.NewRenderer()
+	require.NoError(t, r.ParseDir("tests/gen/tmpl"))
+	cli, err := brief.DecodeFile("tests/cli.brief")
+	require.NoError(t, err)
+	_, err = m.Generate(r, cli, t.TempDir(), true)
+	assert.Error(t, err)
+}
+
+func TestLocalPath(t *testing.T) {
+	assert.True(t, brief.LocalPath("cmd/run.go"))
+	assert.True(t, brief.LocalPath("a/../b"))
+	assert.False(t, brief.LocalPath("../x"))
+	assert.False(t, brief.LocalPath("a/../../x"))
+	assert.False(t, brief.LocalPath("/etc/x"))
+	assert.False(t, brief.LocalPath("."))
+}
+
+func TestGenerateFiles(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader("manifest\n    output path:\"{{.Name}}.txt\" template:main"), "tests")
+	require.NoError(t, err)
+	m, err := brief.ParseManifest(nodes, "tests")
+	require.NoError(t, err)
+	r := brief.NewRenderer()
+	require.NoError(t, r.Parse(brief.DefaultEntry, `{{template "help" .}}`))
+	require.NoError(t, r.Parse("help", `default`))
+
+	results := make([]brief.FileResult, 0)
+	for _, text := range []string{"#template help `{{.Name}} help`\nspec:one\n", "spec:two\n"} {
+		dec := brief.NewDecoder(strings.NewReader(text), 4, "")
+		roots, err := dec.Decode()
+		require.NoError(t, err)
+		results = append(results, brief.FileResult{Nodes: roots, Templates: dec.Templates})
+	}
+	dir := t.TempDir()
+	_, err = m.GenerateFiles(r, results, dir, false)
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "one.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "one help", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "two.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "default", string(data), "partials of one file are not used by the next")
+}
+
+func TestGenerateData(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader("manifest\n    output path:\"{{data `dir`}}/{{.Name}}.txt\" template:main"), "tests")
+	require.NoError(t, err)
+	m, err := brief.ParseManifest(nodes, "tests")
+	require.NoError(t, err)
+	roots, err := brief.Decode(strings.NewReader("spec:one\n"), "")
+	require.NoError(t, err)
+	for _, dir := range []string{"first", "second"} {
+		r := brief.NewRenderer()
+		require.NoError(t, r.Parse(brief.DefaultEntry, `{{.Name}}`))
+		r.Data["dir"] = dir
+		gen, err := m.Generate(r, roots, t.TempDir(), true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{dir + "/one.txt"}, gen.Written, "path data of each renderer")
+	}
+}
@@ -0,0 +1,163 @@
+package brief
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"text/template"
+	"unicode"
+)
+
+//go:embed templates/gotypes.tmpl
+var gotypes string
+
+// goTypes is the view of a schema used by the gotypes template
+type goTypes struct {
+	Package string
+	Parse   bool // some key values need strconv
+	Types   []*goType
+	Roots   []*goChild
+}
+
+type goType struct {
+	Name, Type string
+	Keys       []*goKey
+	Children   []*goChild
+}
+
+type goKey struct {
+	Field, Key, GoType, Parse string
+}
+
+type goChild struct {
+	Field, Type, GoType string
+	Many                bool
+}
+
+// GenerateGo writes Go struct definitions and a typed loader for the schema
+// Each element type becomes a struct with Name, Content, a field per key
+// and a field per child type, a slice unless the child max is one.
+// Load and LoadFile build a Document of the top-level elements.
+// A name that is not a Go identifier gets an X prefix, and a type name
+// used twice, or by the generated Document, Load, LoadFile and New funcs,
+// gets an Elem suffix.
+func GenerateGo(out io.Writer, schema *Schema, pkg string) error {
+	if !token.IsIdentifier(pkg) || pkg == "_" {
+		return fmt.Errorf("package name %q is not a Go identifier", pkg)
+	}
+	view := &goTypes{Package: pkg}
+	names := goTypeNames(schema)
+	for _, elem := range schema.Elements {
+		typ := &goType{Name: names[elem.Type], Type: elem.Type}
+		fields := map[string]bool{"Name": true, "Content": true, "Node": true}
+		for _, key := range elem.Keys {
+			gk := &goKey{Field: uniqueField(fields, goIdent(key.Name), "Key"), Key: key.Name, GoType: "string"}
+			switch key.Type {
+			case TypeBool:
+				gk.GoType, gk.Parse = "bool", "strconv.ParseBool(val)"
+			case TypeInt:
+				gk.GoType, gk.Parse = "int", "strconv.Atoi(val)"
+			case TypeFloat:
+				gk.GoType, gk.Parse = "float64", "strconv.ParseFloat(val, 64)"
+			}
+			if len(gk.Parse) > 0 {
+				view.Parse = true
+			}
+			typ.Keys = append(typ.Keys, gk)
+		}
+		for _, child := range elem.Children {
+			if schema.Element(child.Type) == nil {
+				continue
+			}
+			gc := &goChild{Type: child.Type, GoType: names[child.Type], Many: child.Max != 1}
+			if gc.Many {
+				gc.Field = uniqueField(fields, Plural(gc.GoType), "List")
+			} else {
+				gc.Field = uniqueField(fields, gc.GoType, "Elem")
+			}
+			typ.Children = append(typ.Children, gc)
+		}
+		view.Types = append(view.Types, typ)
+	}
+	roots := map[string]bool{}
+	for _, elem := range schemaRoots(schema) {
+		name := names[elem.Type]
+		view.Roots = append(view.Roots, &goChild{Field: uniqueField(roots, Plural(name), "List"), Type: elem.Type, GoType: name, Many: true})
+	}
+	tmpl, err := template.New("gotypes").Parse(gotypes)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(src)
+	return err
+}
+
+// schemaRoots are the root elements or else elements that are not a child
+func schemaRoots(schema *Schema) []*ElementSchema {
+	roots := make([]*ElementSchema, 0)
+	if schema.hasRoots() {
+		for _, elem := range schema.Elements {
+			if elem.Root {
+				roots = append(roots, elem)
+			}
+		}
+		return roots
+	}
+	children := map[string]bool{}
+	for _, elem := range schema.Elements {
+		for _, child := range elem.Children {
+			children[child.Type] = true
+		}
+	}
+	for _, elem := range schema.Elements {
+		if !children[elem.Type] {
+			roots = append(roots, elem)
+		}
+	}
+	return roots
+}
+
+// uniqueField name among the fields of a struct, suffix is added to a duplicate
+func uniqueField(fields map[string]bool, name, suffix string) string {
+	for fields[name] {
+		name += suffix
+	}
+	fields[name] = true
+	return name
+}
+
+// goIdent is the exported Go identifier for a brief name
+func goIdent(name string) string {
+	id := Pascal(name)
+	if len(id) == 0 || !token.IsIdentifier(id) || !unicode.IsUpper([]rune(id)[0]) {
+		id = "X" + id
+	}
+	return id
+}
+
+// goTypeNames of the element types, each type name and its New func are
+// unique among the package level names
+func goTypeNames(schema *Schema) map[string]string {
+	used := map[string]bool{"Document": true, "Load": true, "LoadFile": true}
+	names := map[string]string{}
+	for _, elem := range schema.Elements {
+		name := goIdent(elem.Type)
+		for used[name] || used["New"+name] {
+			name += "Elem"
+		}
+		used[name], used["New"+name] = true, true
+		names[elem.Type] = name
+	}
+	return names
+}
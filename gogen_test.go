@@ -0,0 +1,189 @@
+package brief_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateGo(t *testing.T) {
+	schema, err := brief.LoadSchema("tests/cli.schema.brief")
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, brief.GenerateGo(&out, schema, "cfg"))
+	src := out.String()
+	t.Log(src)
+	_, err = parser.ParseFile(token.NewFileSet(), "cfg.go", src, 0)
+	require.NoError(t, err)
+	flat := strings.Join(strings.Fields(src), " ")
+	for _, expect := range []string{
+		"package cfg",
+		"type Cli struct",
+		"Version float64",
+		"Commands []*Command",
+		"Hidden bool",
+		"Flags []*Flag",
+		"Count int",
+		"Clis []*Cli",
+		`strconv.ParseBool(val)`,
+		"func LoadFile(filename string) (*Document, error)",
+	} {
+		assert.Contains(t, flat, expect)
+	}
+}
+
+func TestGenerateGoNames(t *testing.T) {
+	text := "schema\n" +
+		"    element:document root:true\n" +
+		"        key:\"2fa\"\n" +
+		"        key:node\n" +
+		"        child:load\n" +
+		"        child:load_file\n" +
+		"        child:\"3d\"\n" +
+		"        child:func\n" +
+		"        child:x\n" +
+		"        child:new_x\n" +
+		"    element:load\n" +
+		"    element:load_file\n" +
+		"    element:\"3d\"\n" +
+		"    element:func\n" +
+		"    element:x\n" +
+		"    element:new_x\n" +
+		"    element:Document root:true\n"
+	nodes, err := brief.Decode(strings.NewReader(text), "")
+	require.NoError(t, err)
+	schema, err := brief.ParseSchema(nodes)
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, brief.GenerateGo(&out, schema, "cfg"))
+	src := out.String()
+	file, err := parser.ParseFile(token.NewFileSet(), "cfg.go", src, 0)
+	require.NoError(t, err, src)
+
+	// every package level name is declared once
+	declared := map[string]int{}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			declared[decl.Name.Name]++
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if typ, ok := spec.(*ast.TypeSpec); ok {
+					declared[typ.Name.Name]++
+				}
+			}
+		}
+	}
+	for name, count := range declared {
+		assert.Equal(t, 1, count, name)
+	}
+	for _, name := range []string{"Document", "Load", "LoadFile", "DocumentElem", "NewDocumentElem",
+		"LoadElem", "LoadFileElem", "X3d", "Func", "X", "NewXElem", "DocumentElemElem"} {
+		assert.Contains(t, declared, name)
+	}
+	flat := strings.Join(strings.Fields(src), " ")
+	assert.Contains(t, flat, "X2fa string")
+	assert.Contains(t, flat, "NodeKey string")
+	assert.Contains(t, flat, "LoadElems []*LoadElem")
+	assert.Contains(t, flat, `case "3d": child, err := NewX3d(sub)`)
+	assert.Contains(t, flat, "DocumentElems []*DocumentElem DocumentElemElems []*DocumentElemElem")
+
+	for _, pkg := range []string{"func", "3d", "_", "my-pkg"} {
+		assert.Error(t, brief.GenerateGo(&out, schema, pkg), pkg)
+	}
+}
+
+func TestGenerateGoQuoting(t *testing.T) {
+	text := "schema\n" +
+		"    element:cfg root:true\n" +
+		"        key:size type:int\n" +
+		"        key:name\n" +
+		"        child:item\n" +
+		"    element:item\n"
+	nodes, err := brief.Decode(strings.NewReader(text), "")
+	require.NoError(t, err)
+	schema, err := brief.ParseSchema(nodes)
+	require.NoError(t, err)
+	// names that are not valid inside a Go string literal as they are
+	schema.Elements[0].Keys[0].Name = `size "in" 100%`
+	schema.Elements[0].Keys[1].Name = `C:\names\q`
+	schema.Elements[0].Children[0].Type = `it"em\`
+	schema.Elements[1].Type = `it"em\`
+	var out strings.Builder
+	require.NoError(t, brief.GenerateGo(&out, schema, "cfg"))
+	src := out.String()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "cfg.go", src, 0)
+	require.NoError(t, err, src)
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("cfg", fset, []*ast.File{file}, nil)
+	require.NoError(t, err, src)
+	assert.Contains(t, src, `node.Keys["size \"in\" 100%"]`)
+	assert.Contains(t, src, `case "it\"em\\":`)
+}
+
+func TestInferSchema(t *testing.T) {
+	nodes, err := brief.DecodeFile("tests/cli.brief")
+	require.NoError(t, err)
+	schema := brief.InferSchema(nodes)
+	require.Len(t, schema.Elements, 3)
+
+	cli := schema.Element("cli")
+	assert.True(t, cli.Root)
+	assert.Equal(t, brief.TypeFloat, cli.Key("version").Type)
+	assert.Equal(t, 1, cli.Child("command").Min)
+	assert.Equal(t, -1, cli.Child("command").Max)
+
+	cmd := schema.Element("command")
+	assert.False(t, cmd.Root)
+	assert.True(t, cmd.Name.Required)
+	assert.Equal(t, brief.TypeIdent, cmd.Name.Type)
+	assert.False(t, cmd.Key("usage").Required)
+	assert.Equal(t, brief.TypeBool, cmd.Key("hidden").Type)
+	assert.Equal(t, 0, cmd.Child("flag").Min)
+
+	flag := schema.Element("flag")
+	assert.True(t, flag.Key("short").Required)
+	assert.Equal(t, brief.TypeInt, flag.Key("count").Type)
+
+	assert.NoError(t, brief.Validate(nodes, schema).Err())
+}
+
+func TestEncodeInferredSchema(t *testing.T) {
+	nodes, err := brief.DecodeFile("tests/cli.brief")
+	require.NoError(t, err)
+	schema := brief.InferSchema(nodes)
+	out := string(schema.Encode())
+	t.Logf("\n%s", out)
+	expect := `schema
+    element:cli count:1 root:true
+        name count:1 required:true type:ident
+        key:version count:1 required:true type:float
+        child:command min:1
+    element:command count:2 parents:cli
+        name count:2 required:true type:ident
+        key:usage count:1
+        key:hidden count:1 type:bool
+        child:flag
+    element:flag count:2 parents:command
+        name count:2 required:true type:ident
+        key:short count:2 required:true type:ident
+        key:count count:1 type:int
+`
+	assert.Equal(t, expect, out)
+
+	decoded, err := brief.Decode(strings.NewReader(out), "tests")
+	require.NoError(t, err)
+	again, err := brief.ParseSchema(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, out, string(again.Encode()))
+}
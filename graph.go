@@ -0,0 +1,135 @@
+package brief
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Graph of the #include dependencies between files
+type Graph struct {
+	Files []string     // the files decoded, in order
+	Edges []Dependency // in the order of the includes, without duplicates
+}
+
+// Dependency of a file on a file it includes
+type Dependency struct {
+	File, Include string
+	Line          int // of the #include in File
+}
+
+// IncludeGraph of decoded files from the includes of each result
+// the files and includes are absolute paths, see Graph.Rel
+func IncludeGraph(results []FileResult) *Graph {
+	graph := &Graph{Files: make([]string, 0, len(results)), Edges: make([]Dependency, 0)}
+	seen := map[Dependency]bool{}
+	for _, result := range results {
+		graph.Files = append(graph.Files, absPath(result.Path))
+		for _, inc := range result.Includes {
+			edge := Dependency{File: absPath(inc.Pos.Filename), Include: absPath(inc.Path), Line: inc.Pos.Line}
+			if seen[edge] {
+				continue
+			}
+			seen[edge] = true
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	return graph
+}
+
+// Includes of the file, directly
+func (g *Graph) Includes(file string) []string {
+	found := make([]string, 0)
+	for _, edge := range g.Edges {
+		if edge.File == file && !contains(found, edge.Include) {
+			found = append(found, edge.Include)
+		}
+	}
+	return found
+}
+
+// Closure of the file, every file it includes directly or not
+func (g *Graph) Closure(file string) []string {
+	found := make([]string, 0)
+	var visit func(file string)
+	visit = func(file string) {
+		for _, inc := range g.Includes(file) {
+			if !contains(found, inc) {
+				found = append(found, inc)
+				visit(inc)
+			}
+		}
+	}
+	visit(file)
+	return found
+}
+
+// Rel returns the graph with paths relative to dir, paths outside dir are not changed
+func (g *Graph) Rel(dir string) *Graph {
+	dir = absPath(dir)
+	rel := func(path string) string {
+		if !within(dir, path) {
+			return path
+		}
+		if r, err := filepath.Rel(dir, path); err == nil {
+			return r
+		}
+		return path
+	}
+	graph := &Graph{Files: make([]string, len(g.Files)), Edges: make([]Dependency, len(g.Edges))}
+	for i, file := range g.Files {
+		graph.Files[i] = rel(file)
+	}
+	for i, edge := range g.Edges {
+		graph.Edges[i] = Dependency{File: rel(edge.File), Include: rel(edge.Include), Line: edge.Line}
+	}
+	return graph
+}
+
+// WriteList writes a file:line: include line for each dependency
+func (g *Graph) WriteList(out io.Writer) error {
+	for _, edge := range g.Edges {
+		if _, err := fmt.Fprintf(out, "%s:%d: %s\n", edge.File, edge.Line, edge.Include); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMake writes a Makefile rule for each file with every file it includes
+//
+//	spec.brief: header.brief footer.brief
+func (g *Graph) WriteMake(out io.Writer) error {
+	for _, file := range g.Files {
+		deps := g.Closure(file)
+		if len(deps) == 0 {
+			continue
+		}
+		for i, dep := range deps {
+			deps[i] = makeEscape(dep)
+		}
+		if _, err := fmt.Fprintf(out, "%s: %s\n", makeEscape(file), strings.Join(deps, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeEscape a path for a make rule, where $ expands and # starts a comment
+var makeEscape = strings.NewReplacer(" ", "\\ ", "$", "$$", "#", "\\#").Replace
+
+// WriteDOT writes the graph in Graphviz DOT, edges are labelled with the line
+func (g *Graph) WriteDOT(out io.Writer) error {
+	var text strings.Builder
+	text.WriteString("digraph includes {\n")
+	for _, file := range g.Files {
+		fmt.Fprintf(&text, "\t%q;\n", file)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&text, "\t%q -> %q [label=\"%d\"];\n", edge.File, edge.Include, edge.Line)
+	}
+	text.WriteString("}\n")
+	_, err := io.WriteString(out, text.String())
+	return err
+}
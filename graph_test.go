@@ -0,0 +1,44 @@
+package brief_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludeGraph(t *testing.T) {
+	results := brief.DecodeFiles([]string{"tests/nested.brief", "tests/cli.brief", "tests/badinclude.brief"}, brief.DecodeOptions{})
+	graph := brief.IncludeGraph(results).Rel(wd(t))
+	require.Len(t, graph.Files, 3)
+	assert.Equal(t, []string{"tests/partials.brief", "tests/partials-usage.brief"}, graph.Closure("tests/nested.brief"))
+	assert.Equal(t, []string{"tests/partials.brief"}, graph.Includes("tests/nested.brief"))
+
+	var list strings.Builder
+	require.NoError(t, graph.WriteList(&list))
+	assert.Equal(t, "tests/nested.brief:2: tests/partials.brief\n"+
+		"tests/partials.brief:1: tests/partials-usage.brief\n"+
+		"tests/badinclude.brief:2: tests/no_such_file.brief\n", list.String())
+
+	var make strings.Builder
+	require.NoError(t, graph.WriteMake(&make))
+	assert.Equal(t, "tests/nested.brief: tests/partials.brief tests/partials-usage.brief\n"+
+		"tests/badinclude.brief: tests/no_such_file.brief\n", make.String())
+
+	var dot strings.Builder
+	require.NoError(t, graph.WriteDOT(&dot))
+	assert.Contains(t, dot.String(), "\"tests/nested.brief\" -> \"tests/partials.brief\" [label=\"2\"];\n")
+	assert.True(t, strings.HasPrefix(dot.String(), "digraph includes {\n"))
+}
+
+func TestWriteMakeEscape(t *testing.T) {
+	graph := &brief.Graph{
+		Files: []string{"my specs/page#1.brief"},
+		Edges: []brief.Dependency{{File: "my specs/page#1.brief", Include: "parts/$head.brief", Line: 1}},
+	}
+	var make strings.Builder
+	require.NoError(t, graph.WriteMake(&make))
+	assert.Equal(t, "my\\ specs/page\\#1.brief: parts/$$head.brief\n", make.String())
+}
@@ -0,0 +1,162 @@
+package brief
+
+import "strconv"
+
+// InferSchema from example nodes
+// every element type seen is declared with the keys and children it was
+// seen with, the types of key values are inferred from the values seen.
+// The result is a starting point to be tightened by hand.
+func InferSchema(nodes []*Node) *Schema {
+	inf := &inference{schema: &Schema{}}
+	for _, node := range nodes {
+		elem := inf.observe(node, nil)
+		elem.Root = true
+	}
+	inf.finish()
+	return inf.schema
+}
+
+type inference struct {
+	schema *Schema
+	seen   map[*ElementSchema]*elementStats
+}
+
+type elementStats struct {
+	count    int
+	named    int
+	keys     map[string]int
+	children map[string]*childStats
+}
+
+type childStats struct {
+	present int // parents with at least one child of the type
+	max     int // most children of the type in one parent
+}
+
+// observe the node and its body, returns the element schema for the node
+func (inf *inference) observe(node *Node, parent *ElementSchema) *ElementSchema {
+	if inf.seen == nil {
+		inf.seen = map[*ElementSchema]*elementStats{}
+	}
+	elem := inf.schema.Element(node.Type)
+	if elem == nil {
+		elem = &ElementSchema{Type: node.Type}
+		inf.schema.Elements = append(inf.schema.Elements, elem)
+		inf.seen[elem] = &elementStats{keys: map[string]int{}, children: map[string]*childStats{}}
+	}
+	stats := inf.seen[elem]
+	stats.count++
+	if parent != nil && !contains(elem.Parents, parent.Type) {
+		elem.Parents = append(elem.Parents, parent.Type)
+	}
+	if node.HasName() {
+		stats.named++
+		if elem.Name == nil {
+			elem.Name = &KeySchema{Name: "name", Type: inferType(node.Name)}
+		} else {
+			elem.Name.Type = mergeType(elem.Name.Type, inferType(node.Name))
+		}
+	}
+	for _, name := range sortedKeys(node.Keys) {
+		kind := inferType(node.Keys[name])
+		stats.keys[name]++
+		if key := elem.Key(name); key != nil {
+			key.Type = mergeType(key.Type, kind)
+			continue
+		}
+		elem.Keys = append(elem.Keys, &KeySchema{Name: name, Type: kind})
+	}
+	counts := map[string]int{}
+	for _, sub := range node.Body {
+		inf.observe(sub, elem)
+		if elem.Child(sub.Type) == nil {
+			elem.Children = append(elem.Children, &ChildSchema{Type: sub.Type})
+		}
+		counts[sub.Type]++
+	}
+	for childType, count := range counts {
+		cs, ok := stats.children[childType]
+		if !ok {
+			cs = &childStats{}
+			stats.children[childType] = cs
+		}
+		cs.present++
+		if count > cs.max {
+			cs.max = count
+		}
+	}
+	return elem
+}
+
+// finish sets requirements and cardinality from the stats
+func (inf *inference) finish() {
+	for _, elem := range inf.schema.Elements {
+		stats := inf.seen[elem]
+		elem.Count = stats.count
+		if elem.Name != nil {
+			elem.Name.Required = stats.named == stats.count
+			elem.Name.Count = stats.named
+		}
+		for _, key := range elem.Keys {
+			key.Count = stats.keys[key.Name]
+			key.Required = key.Count == stats.count
+		}
+		for _, child := range elem.Children {
+			cs := stats.children[child.Type]
+			if cs.present == stats.count {
+				child.Min = 1
+			}
+			child.Max = -1
+			if cs.max == 1 {
+				child.Max = 1
+			}
+		}
+	}
+}
+
+// inferType of a value, one of the schema value types
+func inferType(value string) string {
+	switch {
+	case value == "true" || value == "false":
+		return TypeBool
+	case isInt(value):
+		return TypeInt
+	case isFloat(value):
+		return TypeFloat
+	case isIdent(value):
+		return TypeIdent
+	}
+	return TypeString
+}
+
+func isInt(value string) bool {
+	_, err := strconv.ParseInt(value, 10, 64)
+	return err == nil
+}
+
+func isFloat(value string) bool {
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+// mergeType of two inferred types into a type that allows both
+func mergeType(a, b string) string {
+	switch {
+	case a == b:
+		return a
+	case (a == TypeInt && b == TypeFloat) || (a == TypeFloat && b == TypeInt):
+		return TypeFloat
+	case (a == TypeBool && b == TypeIdent) || (a == TypeIdent && b == TypeBool):
+		return TypeIdent
+	}
+	return TypeString
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package brief
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonNode is the JSON rendering of a Node
+type jsonNode struct {
+	Type    string            `json:"type"`
+	Name    string            `json:"name,omitempty"`
+	Keys    map[string]string `json:"keys,omitempty"`
+	Content string            `json:"content,omitempty"`
+	Body    []*Node           `json:"body,omitempty"`
+}
+
+// MarshalJSON renders the node as an object with type, name, keys, content and body
+func (node *Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&jsonNode{
+		Type:    node.Type,
+		Name:    node.Name,
+		Keys:    node.Keys,
+		Content: node.Content,
+		Body:    node.Body,
+	})
+}
+
+// WriteJSON for a Node to a writer
+func (node *Node) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(node)
+}
+
+// JSON Schema patterns for key value types, JSON key values are strings
+var jsonValuePatterns = map[string]string{
+	TypeIdent: `^[A-Za-z_][A-Za-z0-9_]*$`,
+	TypeInt:   `^[-+]?[0-9]+$`,
+	TypeFloat: `^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`,
+}
+
+// WriteJSONSchema writes a JSON Schema for the WriteJSON output of documents in the schema
+func (schema *Schema) WriteJSONSchema(out io.Writer) error {
+	type object = map[string]interface{}
+	defs := object{}
+	for _, elem := range schema.Elements {
+		props := object{
+			"type":    object{"const": elem.Type},
+			"name":    jsonValueSchema(TypeString),
+			"content": object{"type": "string"},
+		}
+		required := []string{"type"}
+		if elem.Name != nil {
+			props["name"] = jsonValueSchema(elem.Name.Type)
+			if elem.Name.Required {
+				required = append(required, "name")
+			}
+		}
+		keyProps := object{}
+		keyRequired := []string{}
+		for _, key := range elem.Keys {
+			keyProps[key.Name] = jsonValueSchema(key.Type)
+			if key.Required {
+				keyRequired = append(keyRequired, key.Name)
+			}
+		}
+		keys := object{"type": "object", "properties": keyProps, "additionalProperties": elem.Open}
+		if len(keyRequired) > 0 {
+			keys["required"] = keyRequired
+			required = append(required, "keys")
+		}
+		props["keys"] = keys
+		items := []interface{}{}
+		contains := []interface{}{}
+		minBody := false
+		for _, child := range elem.Children {
+			if schema.Element(child.Type) == nil {
+				continue
+			}
+			ref := object{"$ref": "#/$defs/" + child.Type}
+			items = append(items, ref)
+			rule := object{"contains": ref, "minContains": child.Min}
+			if child.Max >= 0 {
+				rule["maxContains"] = child.Max
+			}
+			contains = append(contains, rule)
+			if child.Min > 0 {
+				minBody = true
+			}
+		}
+		if len(items) > 0 {
+			body := object{"type": "array", "items": object{"anyOf": items}, "allOf": contains}
+			props["body"] = body
+			if minBody {
+				required = append(required, "body")
+			}
+		}
+		defs[elem.Type] = object{
+			"type":                 "object",
+			"properties":           props,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	}
+	roots := []interface{}{}
+	for _, elem := range schemaRoots(schema) {
+		roots = append(roots, object{"$ref": "#/$defs/" + elem.Type})
+	}
+	doc := object{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"anyOf":   roots,
+		"$defs":   defs,
+	}
+	if len(schema.Name) > 0 {
+		doc["title"] = schema.Name
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(doc)
+}
+
+func jsonValueSchema(kind string) map[string]interface{} {
+	switch kind {
+	case TypeBool:
+		return map[string]interface{}{"enum": []string{"true", "false"}}
+	case TypeString:
+		return map[string]interface{}{"type": "string"}
+	}
+	return map[string]interface{}{"type": "string", "pattern": jsonValuePatterns[kind]}
+}
@@ -0,0 +1,204 @@
+package brief
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/scanner"
+)
+
+// Limits bound the resources used by a decode, zero is no limit
+// Counts are totals over the decoded file and all of its includes.
+type Limits struct {
+	MaxDepth        int      // nesting of elements
+	MaxNodes        int      // number of elements
+	MaxContentBytes int      // size of one content
+	MaxBytes        int      // size of the input
+	MaxIncludes     int      // number of #include features
+	MaxIncludeDepth int      // includes within included files
+	IncludeRoots    []string // directories included files must be in, none allows any file
+}
+
+// none true when there are no limits
+func (limits *Limits) none() bool {
+	return limits.MaxDepth == 0 && limits.MaxNodes == 0 && limits.MaxContentBytes == 0 &&
+		limits.MaxBytes == 0 && limits.MaxIncludes == 0 && limits.MaxIncludeDepth == 0 &&
+		len(limits.IncludeRoots) == 0
+}
+
+// LimitError reports the limit exceeded by a decode
+type LimitError struct {
+	Limit string // name of the Limits field
+	Max   int
+	Pos   scanner.Position
+	Msg   string
+}
+
+func (e *LimitError) Error() string {
+	msg := fmt.Sprintf("%s %d exceeded", e.Limit, e.Max)
+	if len(e.Msg) > 0 {
+		msg = e.Msg
+	}
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Pos, msg)
+	}
+	return msg
+}
+
+// usage of the limits shared by a decoder and the decoders of its includes
+type usage struct {
+	nodes, includes, bytes int
+	err                    error // the first limit or context error ends every decoder
+}
+
+// DecodeContext decodes reader within the limits until the context is done
+// the error is a *LimitError, the context error or a decode error
+func DecodeContext(ctx context.Context, reader io.Reader, srcdir string, limits Limits) ([]*Node, error) {
+	dec := NewDecoder(reader, TabCount, srcdir)
+	dec.Context = ctx
+	dec.Limits = limits
+	return dec.Decode()
+}
+
+// input counts the bytes read by a decoder
+type input struct {
+	dec    *Decoder
+	reader io.Reader
+}
+
+func (in *input) Read(p []byte) (int, error) {
+	max := in.dec.Limits.MaxBytes
+	if max <= 0 {
+		return in.reader.Read(p)
+	}
+	use := in.dec.shared()
+	if use.err != nil {
+		return 0, io.EOF
+	}
+	// read a byte past the limit to know it is exceeded
+	if left := max - use.bytes + 1; len(p) > left {
+		p = p[:left]
+	}
+	n, err := in.reader.Read(p)
+	use.bytes += n
+	if use.bytes > max {
+		use.err = &LimitError{Limit: "MaxBytes", Max: max, Msg: fmt.Sprintf("input exceeds %d bytes", max)}
+		return 0, io.EOF
+	}
+	return n, err
+}
+
+// shared usage of the limits
+func (dec *Decoder) shared() *usage {
+	if dec.usage == nil {
+		dec.usage = &usage{}
+	}
+	return dec.usage
+}
+
+// failed returns the error that ends the decode, a limit error comes first
+func (dec *Decoder) failed() error {
+	if dec.usage != nil && dec.usage.err != nil {
+		return dec.usage.err
+	}
+	return dec.Err
+}
+
+// fail ends the decoder and the decoders sharing its usage
+func (dec *Decoder) fail(err error) {
+	dec.shared().err = err
+	dec.Err = err
+}
+
+// exceeded reports a limit
+func (dec *Decoder) exceeded(limit string, max int) {
+	dec.fail(&LimitError{Limit: limit, Max: max, Pos: dec.Text.Position})
+}
+
+// checkContext ends the decode when the context is done
+func (dec *Decoder) checkContext() {
+	if dec.Context == nil {
+		return
+	}
+	if err := dec.Context.Err(); err != nil {
+		dec.fail(err)
+	}
+}
+
+// checkNode limits for a node added at depth
+func (dec *Decoder) checkNode(depth int) {
+	use := dec.shared()
+	use.nodes++
+	switch {
+	case dec.Limits.MaxNodes > 0 && use.nodes > dec.Limits.MaxNodes:
+		dec.exceeded("MaxNodes", dec.Limits.MaxNodes)
+	case dec.Limits.MaxDepth > 0 && dec.depth+depth >= dec.Limits.MaxDepth:
+		dec.exceeded("MaxDepth", dec.Limits.MaxDepth)
+	}
+}
+
+// checkContent limits the size of content
+func (dec *Decoder) checkContent(content string) {
+	if dec.Limits.MaxContentBytes > 0 && len(content) > dec.Limits.MaxContentBytes {
+		dec.exceeded("MaxContentBytes", dec.Limits.MaxContentBytes)
+	}
+}
+
+// checkInclude limits for an included file, false when exceeded
+func (dec *Decoder) checkInclude(filename string) bool {
+	use := dec.shared()
+	use.includes++
+	switch {
+	case dec.Limits.MaxIncludes > 0 && use.includes > dec.Limits.MaxIncludes:
+		dec.exceeded("MaxIncludes", dec.Limits.MaxIncludes)
+		return false
+	case dec.Limits.MaxIncludeDepth > 0 && dec.includeDepth >= dec.Limits.MaxIncludeDepth:
+		dec.exceeded("MaxIncludeDepth", dec.Limits.MaxIncludeDepth)
+		return false
+	case len(dec.Limits.IncludeRoots) > 0 && !inRoots(filename, dec.Limits.IncludeRoots):
+		dec.fail(&LimitError{Limit: "IncludeRoots", Pos: dec.Text.Position,
+			Msg: fmt.Sprintf("include %s is outside the include roots", filename)})
+		return false
+	}
+	return true
+}
+
+// inRoots true if the file is within one of the root directories
+func inRoots(filename string, roots []string) bool {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		dir, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if within(dir, abs) {
+			return true
+		}
+	}
+	return false
+}
+
+// within true if the path is the directory or below it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// limitInclude shares the context and limits with the decoder of an include
+func (dec *Decoder) limitInclude(idec *Decoder) {
+	idec.Context = dec.Context
+	idec.Limits = dec.Limits
+	idec.usage = dec.shared()
+	idec.includeDepth = dec.includeDepth + 1
+	idec.depth = dec.depth
+	for _, open := range dec.Nesting {
+		if open.Indent < idec.Padding {
+			idec.depth++
+		}
+	}
+}
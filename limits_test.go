@@ -0,0 +1,90 @@
+package brief_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	cli, err := os.ReadFile("tests/cli.brief")
+	require.NoError(t, err)
+	tests := []struct {
+		Name   string
+		Text   string
+		Limits brief.Limits
+		Limit  string // exceeded or empty when within the limits
+	}{
+		{"depth", string(cli), brief.Limits{MaxDepth: 2}, "MaxDepth"},
+		{"depth ok", string(cli), brief.Limits{MaxDepth: 3}, ""},
+		{"nodes", string(cli), brief.Limits{MaxNodes: 4}, "MaxNodes"},
+		{"nodes ok", string(cli), brief.Limits{MaxNodes: 5}, ""},
+		{"content", "elem `hello world`\n", brief.Limits{MaxContentBytes: 5}, "MaxContentBytes"},
+		{"block", "elem #|hello world|#\n", brief.Limits{MaxContentBytes: 5}, "MaxContentBytes"},
+		{"bytes", string(cli), brief.Limits{MaxBytes: 100}, "MaxBytes"},
+		{"bytes ok", string(cli), brief.Limits{MaxBytes: len(cli)}, ""},
+		{"includes", "#include `test0.brief`\n#include `test0.brief`\n", brief.Limits{MaxIncludes: 1}, "MaxIncludes"},
+		{"include bytes", "#include `test0.brief`\n", brief.Limits{MaxBytes: 50}, "MaxBytes"},
+		{"include nodes", "#include `test0.brief`\n", brief.Limits{MaxNodes: 3}, "MaxNodes"},
+		{"include depth", "#include `nested.brief`\n", brief.Limits{MaxIncludeDepth: 2}, "MaxIncludeDepth"},
+		{"include depth ok", "#include `nested.brief`\n", brief.Limits{MaxIncludeDepth: 3}, ""},
+		{"nested depth", "#include `nested.brief`\n", brief.Limits{MaxDepth: 2}, "MaxDepth"},
+		{"roots", "#include `test0.brief`\n", brief.Limits{IncludeRoots: []string{"tests/gen"}}, "IncludeRoots"},
+		{"roots ok", "#include `test0.brief`\n", brief.Limits{IncludeRoots: []string{"tests"}}, ""},
+	}
+	for _, test := range tests {
+		_, err := brief.DecodeContext(context.Background(), strings.NewReader(test.Text), "tests", test.Limits)
+		if len(test.Limit) == 0 {
+			assert.NoError(t, err, test.Name)
+			continue
+		}
+		var lerr *brief.LimitError
+		if assert.True(t, errors.As(err, &lerr), "%s: %v", test.Name, err) {
+			assert.Equal(t, test.Limit, lerr.Limit, test.Name)
+		}
+	}
+}
+
+func TestDecodeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := brief.DecodeContext(ctx, strings.NewReader("elem\n    sub\n"), "", brief.Limits{})
+	assert.True(t, errors.Is(err, context.Canceled), "not canceled: %v", err)
+
+	dec := brief.NewDecoder(strings.NewReader("elem\n    sub\n"), 4, "")
+	dec.Context = ctx
+	_, err = dec.NextEvent()
+	assert.True(t, errors.Is(err, context.Canceled), "stream not canceled: %v", err)
+}
+
+// endless reader of content that never closes a block
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func TestUnterminatedBlock(t *testing.T) {
+	input := func() io.Reader { return io.MultiReader(strings.NewReader("elem #|"), endless{}) }
+	_, err := brief.DecodeContext(context.Background(), input(), "", brief.Limits{MaxContentBytes: 1 << 16})
+	var lerr *brief.LimitError
+	require.True(t, errors.As(err, &lerr), "%v", err)
+	assert.Equal(t, "MaxContentBytes", lerr.Limit)
+	assert.Equal(t, 1, lerr.Pos.Line, "positioned at the block")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = brief.DecodeContext(ctx, input(), "", brief.Limits{})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "not canceled: %v", err)
+}
@@ -0,0 +1,277 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/robbyriverside/brief"
+)
+
+// Document is an open brief file decoded for the language features
+// Positions assume one column per byte, which holds for ASCII documents.
+type Document struct {
+	URI, Path, Text string
+	Nodes           []*brief.Node   // roots, decoded so far when there is an error
+	Includes        []brief.Include // #include features of the document
+	Err             error           // decode error
+	lines           []string
+	order           []*brief.Node // nodes of this file in document order
+	depths          []int         // of the nodes in order
+	parents         []int         // order index of the parent in this file, or -1
+	ranges          []Range       // of the nodes in order with their body
+}
+
+// NewDocument decodes the text of the document
+func NewDocument(uri, text string) *Document {
+	doc := &Document{URI: uri, Path: uriPath(uri), Text: text, lines: strings.Split(text, "\n")}
+	dec := brief.NewDecoder(strings.NewReader(text), brief.TabCount, filepath.Dir(doc.Path))
+	dec.Text.Filename = doc.Path
+	doc.Nodes, doc.Err = dec.Decode()
+	if doc.Err != nil {
+		doc.Nodes = dec.Roots
+	}
+	doc.Includes = dec.Includes
+	brief.Walk(doc.Nodes, func(node *brief.Node, depth int) brief.WalkAction {
+		if !doc.local(node) {
+			return brief.SkipChildren
+		}
+		doc.order = append(doc.order, node)
+		doc.depths = append(doc.depths, depth)
+		return brief.Continue
+	})
+	doc.index()
+	return doc
+}
+
+// index the range and parent of every node in one pass
+// a node ends before the next node that is not in its body
+func (doc *Document) index() {
+	doc.ranges = make([]Range, len(doc.order))
+	doc.parents = make([]int, len(doc.order))
+	open := make([]int, 0) // nodes whose range has not ended
+	end := func(at, line int) {
+		start := position(doc.order[at].Pos)
+		for line > start.Line && len(strings.TrimSpace(doc.lines[line])) == 0 {
+			line--
+		}
+		doc.ranges[at] = Range{Start: start, End: Position{Line: line, Character: len(doc.lines[line])}}
+	}
+	for at, node := range doc.order {
+		for size := len(open); size > 0 && doc.depths[open[size-1]] >= doc.depths[at]; size = len(open) {
+			end(open[size-1], node.Pos.Line-2)
+			open = open[:size-1]
+		}
+		doc.parents[at] = -1
+		if size := len(open); size > 0 {
+			doc.parents[at] = open[size-1]
+		}
+		open = append(open, at)
+	}
+	for _, at := range open {
+		end(at, len(doc.lines)-1)
+	}
+}
+
+// uriPath of a file URI, other URIs are used as they are
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathURI of a file path
+func pathURI(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+// local true when the node was decoded from this document, not an include
+func (doc *Document) local(node *brief.Node) bool {
+	return node.Pos.Filename == doc.Path
+}
+
+// Diagnostics for the decode errors, the decoder chains one error to the next
+func (doc *Document) Diagnostics() []Diagnostic {
+	diags := make([]Diagnostic, 0)
+	for err := doc.Err; err != nil; {
+		derr, ok := err.(*brief.DecodeError)
+		if !ok {
+			diags = append(diags, Diagnostic{Severity: SeverityError, Source: "brief", Message: err.Error()})
+			break
+		}
+		diags = append(diags, Diagnostic{
+			Range:    tokenRange(derr.Pos, derr.Token),
+			Severity: SeverityError,
+			Source:   "brief",
+			Message:  derr.Msg,
+		})
+		err = derr.Err
+	}
+	return diags
+}
+
+// tokenRange of a token at pos, only the first line of a token is used
+func tokenRange(pos scanner.Position, token string) Range {
+	start := position(pos)
+	if nl := strings.IndexRune(token, '\n'); nl >= 0 {
+		token = token[:nl]
+	}
+	return Range{Start: start, End: Position{Line: start.Line, Character: start.Character + len(token)}}
+}
+
+// position in a document from a scanner position
+func position(pos scanner.Position) Position {
+	line, column := pos.Line-1, pos.Column-1
+	if line < 0 {
+		line = 0
+	}
+	if column < 0 {
+		column = 0
+	}
+	return Position{Line: line, Character: column}
+}
+
+// Symbols the element outline of the document
+func (doc *Document) Symbols() []DocumentSymbol {
+	syms, _ := doc.symbols(0, 0)
+	return syms
+}
+
+// symbols of nodes in order from at with the depth, returns the next position
+func (doc *Document) symbols(at, depth int) ([]DocumentSymbol, int) {
+	syms := make([]DocumentSymbol, 0)
+	for at < len(doc.order) {
+		node := doc.order[at]
+		if doc.depths[at] < depth {
+			break
+		}
+		sym := DocumentSymbol{
+			Name:           spec(node),
+			Detail:         keys(node),
+			Kind:           SymbolObject,
+			Range:          doc.ranges[at],
+			SelectionRange: tokenRange(node.Pos, node.Type),
+		}
+		if node.NoBody() {
+			sym.Kind = SymbolField
+		}
+		sym.Children, at = doc.symbols(at+1, depth+1)
+		if len(sym.Children) == 0 {
+			sym.Children = nil
+		}
+		syms = append(syms, sym)
+	}
+	return syms, at
+}
+
+func spec(node *brief.Node) string {
+	if node.HasName() {
+		return node.Type + ":" + node.Name
+	}
+	return node.Type
+}
+
+// keys of a node as brief key values
+func keys(node *brief.Node) string {
+	head := header(node, 0)
+	return strings.TrimSpace(strings.TrimPrefix(head, spec(node)))
+}
+
+// header line of a node in brief format without content
+func header(node *brief.Node, indent int) string {
+	head := &brief.Node{Type: node.Type, Name: node.Name, Keys: node.Keys, Indent: indent}
+	return strings.TrimRight(string(head.Encode()), "\n")
+}
+
+// at returns the innermost node whose range contains the line
+// ranges are nested, so only the last node starting on or before the line
+// and its parents can contain it
+func (doc *Document) at(line int) *brief.Node {
+	at := sort.Search(len(doc.order), func(i int) bool {
+		return doc.ranges[i].Start.Line > line
+	}) - 1
+	for ; at >= 0; at = doc.parents[at] {
+		if line <= doc.ranges[at].End.Line {
+			return doc.order[at]
+		}
+	}
+	return nil
+}
+
+// Hover shows the context chain from the root to the element at the position
+func (doc *Document) Hover(pos Position) *Hover {
+	node := doc.at(pos.Line)
+	if node == nil {
+		return nil
+	}
+	chain := make([]*brief.Node, 0)
+	for at := node; at != nil; at = at.Parent {
+		chain = append([]*brief.Node{at}, chain...)
+	}
+	var text strings.Builder
+	text.WriteString("```brief\n")
+	for depth, at := range chain {
+		text.WriteString(header(at, depth*brief.TabCount) + "\n")
+	}
+	text.WriteString("```\n")
+	text.WriteString(node.Path())
+	r := tokenRange(node.Pos, node.Type)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text.String()}, Range: &r}
+}
+
+// Definition of an #include file name at the position
+func (doc *Document) Definition(pos Position) []Location {
+	for _, inc := range doc.Includes {
+		if inc.Pos.Filename != doc.Path || position(inc.Pos).Line != pos.Line {
+			continue
+		}
+		return []Location{{URI: pathURI(inc.Path)}}
+	}
+	return nil
+}
+
+// Format the document with the brief encoder
+// documents with errors, comments or features are left alone since the
+// encoder does not write them
+func (doc *Document) Format() []TextEdit {
+	if doc.Err != nil || !doc.formattable() {
+		return nil
+	}
+	var out strings.Builder
+	for _, node := range doc.Nodes {
+		out.Write(node.Encode())
+	}
+	text := out.String()
+	if text == doc.Text {
+		return []TextEdit{}
+	}
+	last := len(doc.lines) - 1
+	whole := Range{End: Position{Line: last, Character: len(doc.lines[last])}}
+	return []TextEdit{{Range: whole, NewText: text}}
+}
+
+// formattable true when the encoder can write the document without loss
+func (doc *Document) formattable() bool {
+	var text brief.Scanner
+	text.Init(strings.NewReader(doc.Text), brief.TabCount)
+	text.Error = func(*scanner.Scanner, string) {}
+	for tok := text.Scan(); tok != scanner.EOF; tok = text.Scan() {
+		switch {
+		case tok == scanner.Comment:
+			return false
+		case tok == '#' && text.LineStart:
+			return false
+		}
+	}
+	for _, node := range doc.order {
+		if strings.ContainsRune(node.Content, '`') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+// Package lsp is a Language Server Protocol server for brief files
+//
+// The server speaks JSON-RPC over a stream, usually stdin and stdout, and
+// provides diagnostics, document symbols, go-to-definition for #include,
+// hover with the context chain of an element and formatting.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Server for brief documents
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*Document
+	shutdown bool
+}
+
+// NewServer reading requests from in and writing responses to out
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*Document{},
+	}
+}
+
+// Run serves requests until the exit notification or the end of input
+func (s *Server) Run() error {
+	for {
+		data, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			if err := s.replyError(nil, &ResponseError{Code: ParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit before shutdown")
+			}
+			return nil
+		}
+		result, err := s.handle(&req)
+		if req.ID == nil {
+			continue // notifications have no response
+		}
+		if err != nil {
+			rerr, ok := err.(*ResponseError)
+			if !ok {
+				rerr = &ResponseError{Code: InternalError, Message: err.Error()}
+			}
+			err = s.replyError(req.ID, rerr)
+		} else {
+			err = s.write(&response{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// read the content of the next message
+func (s *Server) read() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("lsp header: %w", err)
+	}
+	size, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp header: invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(s.in, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// write a message with its header
+func (s *Server) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+func (s *Server) replyError(id *json.RawMessage, rerr *ResponseError) error {
+	return s.write(&errorResponse{JSONRPC: "2.0", ID: id, Error: rerr})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(&notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// handle a request and return the result
+func (s *Server) handle(req *request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return &InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:           syncFull,
+				DocumentSymbolProvider:     true,
+				DefinitionProvider:         true,
+				HoverProvider:              true,
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "brief"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.open(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		size := len(params.ContentChanges)
+		if size == 0 {
+			return nil, nil
+		}
+		return nil, s.open(params.TextDocument.URI, params.ContentChanges[size-1].Text)
+	case "textDocument/didClose":
+		var params documentParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics",
+			&PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	case "textDocument/documentSymbol":
+		doc, err := s.document(req.Params)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Symbols(), nil
+	case "textDocument/formatting":
+		doc, err := s.document(req.Params)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Format(), nil
+	case "textDocument/definition":
+		var params positionParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.lookup(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Definition(params.Position), nil
+	case "textDocument/hover":
+		var params positionParams
+		if err := unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.lookup(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Hover(params.Position), nil
+	}
+	if req.ID == nil || strings.HasPrefix(req.Method, "$/") {
+		return nil, nil // unknown notifications are ignored
+	}
+	return nil, &ResponseError{Code: MethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// open decodes the document text and publishes its diagnostics
+func (s *Server) open(uri, text string) error {
+	doc := NewDocument(uri, text)
+	s.docs[uri] = doc
+	return s.notify("textDocument/publishDiagnostics",
+		&PublishDiagnosticsParams{URI: uri, Diagnostics: doc.Diagnostics()})
+}
+
+func (s *Server) document(params json.RawMessage) (*Document, error) {
+	var doc documentParams
+	if err := unmarshal(params, &doc); err != nil {
+		return nil, err
+	}
+	return s.lookup(doc.TextDocument.URI)
+}
+
+func (s *Server) lookup(uri string) (*Document, error) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &ResponseError{Code: InvalidParams, Message: "document not open: " + uri}
+	}
+	return doc, nil
+}
+
+func unmarshal(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &ResponseError{Code: InvalidParams, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *lsp.ResponseError
+}
+
+// session runs the server with the requests and returns the messages written
+func session(t *testing.T, requests ...interface{}) []message {
+	var in bytes.Buffer
+	for _, req := range requests {
+		data, err := json.Marshal(req)
+		require.NoError(t, err)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+	var out bytes.Buffer
+	require.NoError(t, lsp.NewServer(&in, &out).Run())
+	msgs := make([]message, 0)
+	read := bufio.NewReader(&out)
+	for {
+		header, err := textproto.NewReader(read).ReadMIMEHeader()
+		if err == io.EOF {
+			return msgs
+		}
+		require.NoError(t, err)
+		size, err := strconv.Atoi(header.Get("Content-Length"))
+		require.NoError(t, err)
+		data := make([]byte, size)
+		_, err = io.ReadFull(read, data)
+		require.NoError(t, err)
+		var msg message
+		require.NoError(t, json.Unmarshal(data, &msg))
+		msgs = append(msgs, msg)
+	}
+}
+
+func call(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notify(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func doc(uri string) map[string]interface{} {
+	return map[string]interface{}{"textDocument": map[string]string{"uri": uri}}
+}
+
+func at(uri string, line, char int) map[string]interface{} {
+	params := doc(uri)
+	params["position"] = lsp.Position{Line: line, Character: char}
+	return params
+}
+
+func result(t *testing.T, msgs []message, id int, v interface{}) {
+	for _, msg := range msgs {
+		if msg.ID != nil && *msg.ID == id {
+			require.Nil(t, msg.Error, "request %d failed", id)
+			require.NoError(t, json.Unmarshal(msg.Result, v))
+			return
+		}
+	}
+	t.Fatalf("no response to request %d", id)
+}
+
+func diagnostics(t *testing.T, msgs []message) [][]lsp.Diagnostic {
+	found := make([][]lsp.Diagnostic, 0)
+	for _, msg := range msgs {
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params lsp.PublishDiagnosticsParams
+		require.NoError(t, json.Unmarshal(msg.Params, &params))
+		found = append(found, params.Diagnostics)
+	}
+	return found
+}
+
+const spec = `cli:tool version:"1.2"
+    command:run usage:"run the tool"
+        flag:verbose short:v
+    command:list
+`
+
+func TestServer(t *testing.T) {
+	dir, err := filepath.Abs("../tests")
+	require.NoError(t, err)
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "spec.brief"))
+	msgs := session(t,
+		call(1, "initialize", map[string]interface{}{}),
+		notify("initialized", map[string]interface{}{}),
+		notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri, "languageId": "brief", "text": "cli:tool\n    cmd::run\n"},
+		}),
+		notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]string{"uri": uri},
+			"contentChanges": []map[string]string{{"text": "#include \"partials-usage.brief\"\n" + spec}},
+		}),
+		call(2, "textDocument/documentSymbol", doc(uri)),
+		call(3, "textDocument/hover", at(uri, 3, 10)),
+		call(4, "textDocument/definition", at(uri, 0, 12)),
+		call(5, "textDocument/formatting", doc(uri)),
+		call(6, "unknown/method", nil),
+		call(7, "shutdown", nil),
+		notify("exit", nil),
+	)
+
+	var init lsp.InitializeResult
+	result(t, msgs, 1, &init)
+	assert.True(t, init.Capabilities.HoverProvider)
+
+	diags := diagnostics(t, msgs)
+	require.Len(t, diags, 2)
+	require.NotEmpty(t, diags[0], "open with an error")
+	assert.Equal(t, 1, diags[0][0].Range.Start.Line)
+	assert.Empty(t, diags[1], "change fixed the error")
+
+	var syms []lsp.DocumentSymbol
+	result(t, msgs, 2, &syms)
+	require.Len(t, syms, 1)
+	assert.Equal(t, "cli:tool", syms[0].Name)
+	assert.Equal(t, "version:1.2", syms[0].Detail)
+	require.Len(t, syms[0].Children, 2)
+	assert.Equal(t, "command:run", syms[0].Children[0].Name)
+	assert.Equal(t, 2, syms[0].Children[0].Range.Start.Line)
+	assert.Equal(t, 3, syms[0].Children[0].Range.End.Line)
+	assert.Equal(t, "flag:verbose", syms[0].Children[0].Children[0].Name)
+
+	var hover lsp.Hover
+	result(t, msgs, 3, &hover)
+	assert.Contains(t, hover.Contents.Value, "cli:tool version:1.2\n    command:run usage:\"run the tool\"\n        flag:verbose short:v\n")
+	assert.Contains(t, hover.Contents.Value, "cli:tool/command:run/flag:verbose")
+
+	var locs []lsp.Location
+	result(t, msgs, 4, &locs)
+	require.Len(t, locs, 1)
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "partials-usage.brief")), locs[0].URI)
+
+	var edits []lsp.TextEdit
+	result(t, msgs, 5, &edits)
+	assert.Nil(t, edits, "a document with features is not formatted")
+
+	for _, msg := range msgs {
+		if msg.ID != nil && *msg.ID == 6 {
+			require.NotNil(t, msg.Error)
+			assert.Equal(t, lsp.MethodNotFound, msg.Error.Code)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	uri := "file:///tmp/format.brief"
+	msgs := session(t,
+		notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri, "text": "cli:tool  b:\"x\" a:1\n    command:\"run\"\n"},
+		}),
+		call(1, "textDocument/formatting", doc(uri)),
+		call(2, "shutdown", nil),
+		notify("exit", nil),
+	)
+	var edits []lsp.TextEdit
+	result(t, msgs, 1, &edits)
+	require.Len(t, edits, 1)
+	assert.Equal(t, "cli:tool a:1 b:x\n    command:run\n", edits[0].NewText)
+	assert.Equal(t, 2, edits[0].Range.End.Line)
+}
+
+func TestDocumentLines(t *testing.T) {
+	text := "cli:tool\n" +
+		"    command:run\n" +
+		"        flag:verbose\n" +
+		"\n" +
+		"    command:list\n" +
+		"        flag:all `all\n" +
+		"items`\n" +
+		"top\n"
+	doc := lsp.NewDocument("file:///tmp/lines.brief", text)
+	require.NoError(t, doc.Err)
+	paths := []string{
+		"cli:tool",
+		"cli:tool/command:run",
+		"cli:tool/command:run/flag:verbose",
+		"cli:tool",
+		"cli:tool/command:list",
+		"cli:tool/command:list/flag:all",
+		"cli:tool/command:list/flag:all",
+		"top",
+	}
+	for line, path := range paths {
+		hover := doc.Hover(lsp.Position{Line: line})
+		require.NotNil(t, hover, "line %d", line)
+		assert.True(t, strings.HasSuffix(hover.Contents.Value, "\n"+path), "line %d: %s", line, hover.Contents.Value)
+	}
+	syms := doc.Symbols()
+	require.Len(t, syms, 2)
+	assert.Equal(t, 6, syms[0].Range.End.Line)
+	assert.Equal(t, 2, syms[0].Children[0].Range.End.Line, "blank lines are not in the range")
+	assert.Equal(t, 7, syms[1].Range.Start.Line)
+}
@@ -0,0 +1,162 @@
+package brief
+
+import "fmt"
+
+// Overlay patch markers
+const (
+	// PatchKey on an overlay node selects how it is merged
+	PatchKey = "patch"
+	// PatchMerge overlay keys and body into the matching base node (default)
+	PatchMerge = "merge"
+	// PatchReplace the matching base node with the overlay node
+	PatchReplace = "replace"
+	// PatchDelete removes the matching base node
+	PatchDelete = "delete"
+	// DeleteValue as an overlay key value removes the key from the base node
+	DeleteValue = "-"
+)
+
+// Merge an overlay onto base nodes and return the merged nodes
+// Overlay nodes are matched to base nodes by type:name among siblings.
+// A matched node has its keys overridden and its body merged in turn,
+// unmatched overlay nodes are appended.  The patch key changes this:
+// patch:replace swaps the whole base node, patch:delete removes it.
+// A key with the value "-" removes that key from the base node.
+// Neither base nor overlay are modified.
+func Merge(base, overlay []*Node) ([]*Node, error) {
+	body := make([]*Node, len(base))
+	for i, node := range base {
+		body[i] = node.Clone()
+	}
+	return mergeBody(nil, body, overlay, 0)
+}
+
+// Merge an overlay node into a copy of this node
+// The overlay must match the node type:name.
+func (node *Node) Merge(overlay *Node) (*Node, error) {
+	if !matchSpec(overlay).Match(node) {
+		return nil, fmt.Errorf("merge %s does not match %s", matchSpec(overlay), matchSpec(node))
+	}
+	merged, err := Merge([]*Node{node}, []*Node{overlay})
+	if err != nil {
+		return nil, err
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged[0], nil
+}
+
+// matchSpec for a node matches its type and exact name
+func matchSpec(node *Node) *Spec {
+	return &Spec{Type: node.Type, Name: node.Name}
+}
+
+// mergeBody merges overlay nodes into the body of parent
+// offset is added to the indent of overlay nodes copied into the body
+func mergeBody(parent *Node, body, overlay []*Node, offset int) ([]*Node, error) {
+	used := make([]bool, len(body))
+	deleted := make([]bool, len(body))
+	for _, over := range overlay {
+		patch, ok := over.Keys[PatchKey]
+		if !ok {
+			patch = PatchMerge
+		}
+		spec := matchSpec(over)
+		at := -1
+		for i, sub := range body {
+			if !used[i] && spec.Match(sub) {
+				at = i
+				break
+			}
+		}
+		switch patch {
+		case PatchDelete:
+			if at >= 0 {
+				used[at] = true
+				deleted[at] = true
+			}
+		case PatchReplace:
+			node := overlayCopy(over, offset)
+			node.Parent = parent
+			if at < 0 {
+				body = append(body, node)
+				used = append(used, true)
+				deleted = append(deleted, false)
+				continue
+			}
+			body[at] = node
+			used[at] = true
+		case PatchMerge:
+			if at < 0 {
+				node := overlayCopy(over, offset)
+				node.Parent = parent
+				body = append(body, node)
+				used = append(used, true)
+				deleted = append(deleted, false)
+				continue
+			}
+			used[at] = true
+			if err := mergeNode(body[at], over); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown patch %q on %s", patch, spec)
+		}
+	}
+	result := make([]*Node, 0, len(body))
+	for i, sub := range body {
+		if !deleted[i] {
+			result = append(result, sub)
+		}
+	}
+	return result, nil
+}
+
+// mergeNode merges overlay keys, content and body into node
+func mergeNode(node, overlay *Node) error {
+	for key, val := range overlay.Keys {
+		switch {
+		case key == PatchKey:
+		case val == DeleteValue:
+			delete(node.Keys, key)
+		default:
+			node.Put(key, val)
+		}
+	}
+	if overlay.HasContent() {
+		node.Content = overlay.Content
+	}
+	body, err := mergeBody(node, node.Body, overlay.Body, node.Indent-overlay.Indent)
+	if err != nil {
+		return err
+	}
+	node.Body = body
+	return nil
+}
+
+// overlayCopy clones an overlay node without its patch markers
+func overlayCopy(overlay *Node, offset int) *Node {
+	node := overlay.Clone()
+	node.shift(offset)
+	stripPatch(node)
+	return node
+}
+
+// stripPatch removes patch keys, deleted keys and deleted nodes
+func stripPatch(node *Node) {
+	for key, val := range node.Keys {
+		if key == PatchKey || val == DeleteValue {
+			delete(node.Keys, key)
+		}
+	}
+	body := node.Body[:0]
+	for _, sub := range node.Body {
+		if sub.Keys[PatchKey] == PatchDelete {
+			continue
+		}
+		stripPatch(sub)
+		body = append(body, sub)
+	}
+	node.Body = body
+}
@@ -0,0 +1,72 @@
+package brief_test
+
+import (
+	_ "embed"
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed tests/base.brief
+var base string
+
+//go:embed tests/overlay.brief
+var overlay string
+
+func TestMerge(t *testing.T) {
+	baseNodes, err := brief.Decode(strings.NewReader(base), "tests")
+	require.NoError(t, err)
+	overNodes, err := brief.Decode(strings.NewReader(overlay), "tests")
+	require.NoError(t, err)
+
+	nodes, err := brief.Merge(baseNodes, overNodes)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	project := nodes[0]
+	t.Logf("\n%s", project.Encode())
+
+	assert.Equal(t, "1.1", project.Keys["version"])
+	_, ok := project.Keys["owner"]
+	assert.False(t, ok, "owner key not deleted")
+	assert.Equal(t, "9090", project.Child("settings").Keys["port"])
+	assert.Equal(t, "localhost", project.Child("settings").Keys["host"])
+
+	top := project.Child("command:top")
+	require.NotNil(t, top)
+	assert.Equal(t, "overridden", top.Content)
+	assert.NotNil(t, top.Child("flag:verbose"))
+	debug := top.Child("flag:debug")
+	require.NotNil(t, debug)
+	assert.Equal(t, top, debug.Parent)
+	assert.Equal(t, 8, debug.Indent)
+
+	assert.Nil(t, project.Child("command:old"))
+
+	keep := project.Child("command:keep")
+	require.NotNil(t, keep)
+	_, ok = keep.Keys["hidden"]
+	assert.False(t, ok, "replaced node kept base keys")
+	_, ok = keep.Keys[brief.PatchKey]
+	assert.False(t, ok, "patch key not removed")
+	assert.NotNil(t, keep.Child("flag:quiet"))
+
+	added := project.Child("command:new")
+	require.NotNil(t, added)
+	assert.True(t, added.NoBody(), "deleted child of new node was added")
+
+	// inputs are untouched
+	assert.Equal(t, "1.0", baseNodes[0].Keys["version"])
+	assert.NotNil(t, baseNodes[0].Child("command:old"))
+}
+
+func TestMergeBadPatch(t *testing.T) {
+	baseNodes, err := brief.Decode(strings.NewReader("elem:a"), "tests")
+	require.NoError(t, err)
+	overNodes, err := brief.Decode(strings.NewReader("elem:a patch:bogus"), "tests")
+	require.NoError(t, err)
+	_, err = brief.Merge(baseNodes, overNodes)
+	assert.Error(t, err)
+}
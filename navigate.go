@@ -0,0 +1,95 @@
+package brief
+
+import "strings"
+
+// siblings returns the body containing this node
+// a root node is alone since roots are not linked
+func (node *Node) siblings() []*Node {
+	if node.Parent == nil {
+		return []*Node{node}
+	}
+	return node.Parent.Body
+}
+
+// Index of this node in the body of its parent
+func (node *Node) Index() int {
+	for i, sub := range node.siblings() {
+		if sub == node {
+			return i
+		}
+	}
+	return -1
+}
+
+// First true if this node is the first in the body of its parent
+func (node *Node) First() bool {
+	return node.Index() == 0
+}
+
+// Last true if this node is the last in the body of its parent
+func (node *Node) Last() bool {
+	return node.Index() == len(node.siblings())-1
+}
+
+// Next sibling in the body of the parent or nil
+func (node *Node) Next() *Node {
+	body := node.siblings()
+	at := node.Index()
+	if at < 0 || at+1 >= len(body) {
+		return nil
+	}
+	return body[at+1]
+}
+
+// Prev sibling in the body of the parent or nil
+func (node *Node) Prev() *Node {
+	body := node.siblings()
+	at := node.Index()
+	if at <= 0 {
+		return nil
+	}
+	return body[at-1]
+}
+
+// Siblings other nodes in the body of the parent that match the node spec
+// name is either a type or a type:name pair
+func (node *Node) Siblings(name string) []*Node {
+	spec := NewSpec(name)
+	result := make([]*Node, 0)
+	for _, sub := range node.siblings() {
+		if sub != node && spec.Match(sub) {
+			result = append(result, sub)
+		}
+	}
+	return result
+}
+
+// Root of the hierarchy containing this node
+func (node *Node) Root() *Node {
+	at := node
+	for at.Parent != nil {
+		at = at.Parent
+	}
+	return at
+}
+
+// Depth of this node below its root, a root is zero
+func (node *Node) Depth() int {
+	depth := 0
+	for at := node.Parent; at != nil; at = at.Parent {
+		depth++
+	}
+	return depth
+}
+
+// Path of node specs from the root to this node separated by slash
+// such as brevity/project:peak/commands/command:top
+func (node *Node) Path() string {
+	path := make([]string, node.Depth()+1)
+	at := node
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i] = matchSpec(at).String()
+		at = at.Parent
+	}
+	return strings.Join(path, "/")
+}
@@ -0,0 +1,59 @@
+package brief_test
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNavigate(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader(base), "tests")
+	require.NoError(t, err)
+	project := nodes[0]
+
+	top := project.Child("command:top")
+	require.NotNil(t, top)
+	assert.Equal(t, 1, top.Index())
+	assert.False(t, top.First())
+	assert.False(t, top.Last())
+	assert.Equal(t, "settings", top.Prev().Type)
+	assert.Equal(t, "old", top.Next().Name)
+	assert.Len(t, top.Siblings("command"), 2)
+	assert.Len(t, top.Siblings("settings"), 1)
+
+	keep := project.Child("command:keep")
+	assert.True(t, keep.Last())
+	assert.Nil(t, keep.Next())
+	assert.True(t, project.Child("settings").First())
+	assert.Nil(t, project.Child("settings").Prev())
+
+	flag := top.Child("flag:verbose")
+	assert.Equal(t, project, flag.Root())
+	assert.Equal(t, 2, flag.Depth())
+	assert.Equal(t, "project:peak/command:top/flag:verbose", flag.Path())
+	assert.Equal(t, 0, project.Depth())
+	assert.True(t, project.First() && project.Last())
+}
+
+func TestNavigateTemplate(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader(base), "tests")
+	require.NoError(t, err)
+	tmpl := template.Must(template.New("list").Parse(
+		`{{range .Body}}{{if eq .Type "command"}}{{.Name}}{{if .Next}},{{end}}{{end}}{{end}}`))
+	var out strings.Builder
+	require.NoError(t, tmpl.Execute(&out, nodes[0]))
+	assert.Equal(t, "top,old,keep", out.String())
+}
+
+func TestIncludeParent(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader(test2), "tests")
+	require.NoError(t, err)
+	html := nodes[0].Child("html")
+	require.NotNil(t, html)
+	assert.Equal(t, nodes[0], html.Parent)
+	assert.Equal(t, "pages/html/body/div:main/p", html.Find("p").Path())
+}
@@ -2,7 +2,9 @@ package brief
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"text/scanner"
 )
 
 // Node in a brief hierarchy
@@ -13,6 +15,8 @@ type Node struct {
 	Parent     *Node
 	Content    string
 	Indent     int
+	Pos        scanner.Position // source position of the element type
+	Strict     bool             // lookups in the templates of a Renderer fail instead of returning a ValueSpec state
 }
 
 // NewNode create a new Node
@@ -44,11 +48,21 @@ func (node *Node) String() string {
 func (node *Node) Key(name string) string {
 	val, ok := node.Keys[name]
 	if !ok {
-		return "noKey"
+		return NoKey
 	}
 	return val
 }
 
+// KeyErr get key value from node, the error is a *ValueError positioned at
+// this node
+func (node *Node) KeyErr(name string) (string, error) {
+	val, ok := node.Keys[name]
+	if !ok {
+		return "", node.valueError(name, NoKey, fmt.Sprintf("no key %s in %s", name, matchSpec(node)))
+	}
+	return val, nil
+}
+
 // Lookup a value from the above context elements
 // spec can be a single name or dotted pair
 // single name, returns the Name of the context
@@ -57,6 +71,51 @@ func (node *Node) Lookup(spec string) string {
 	return NewValueSpec(spec).Lookup(node)
 }
 
+// LookupErr a value from the above context elements
+// the error is a *ValueError positioned at this node
+// in a template a failed lookup aborts the execution
+func (node *Node) LookupErr(spec string) (string, error) {
+	val, err := NewValueSpec(spec).Resolve(node)
+	if err != nil {
+		verr := err.(*ValueError)
+		verr.Pos = node.Pos
+		return "", verr
+	}
+	return val, nil
+}
+
+// MustLookup a value from the above context elements or panic
+func (node *Node) MustLookup(spec string) string {
+	val, err := node.LookupErr(spec)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+// Require every spec to have a value, returns an empty string
+// use in a template to fail early:  {{ .Require "project.id" "command" }}
+func (node *Node) Require(specs ...string) (string, error) {
+	for _, spec := range specs {
+		if _, err := node.LookupErr(spec); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// SetStrict on this node and its body
+func (node *Node) SetStrict(strict bool) {
+	node.Walk(func(n *Node, depth int) WalkAction {
+		n.Strict = strict
+		return Continue
+	})
+}
+
+func (node *Node) valueError(spec, reason, msg string) *ValueError {
+	return &ValueError{Spec: spec, Reason: reason, Msg: msg, Pos: node.Pos}
+}
+
 // Slice calls Lookup on each spec and returns the slice
 func (node *Node) Slice(specs ...string) []string {
 	found := []string{}
@@ -148,3 +207,39 @@ func (node *Node) Compile() {
 		}
 	}
 }
+
+// Clone returns a deep copy of the node and its body
+// the copy has no Parent, the cloned body is linked to the copy
+func (node *Node) Clone() *Node {
+	clone := *node
+	clone.Parent = nil
+	clone.Keys = make(map[string]string, len(node.Keys))
+	for key, val := range node.Keys {
+		clone.Keys[key] = val
+	}
+	clone.Body = make([]*Node, 0, len(node.Body))
+	for _, sub := range node.Body {
+		child := sub.Clone()
+		child.Parent = &clone
+		clone.Body = append(clone.Body, child)
+	}
+	return &clone
+}
+
+// shift moves the indent of the node and its body by offset
+func (node *Node) shift(offset int) {
+	node.Indent += offset
+	for _, sub := range node.Body {
+		sub.shift(offset)
+	}
+}
+
+// sortedKeys returns the key names in order
+func sortedKeys(keys map[string]string) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
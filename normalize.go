@@ -0,0 +1,95 @@
+package brief
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Normalize nodes using the keys declared in the schema
+// key aliases are renamed, values are coerced to the key type
+// and missing keys with a default are added
+// nodes of element types not in the schema are left alone
+func (schema *Schema) Normalize(nodes []*Node) error {
+	var err error
+	Walk(nodes, func(node *Node, depth int) WalkAction {
+		elem := schema.Element(node.Type)
+		if elem == nil {
+			return Continue
+		}
+		if err = elem.normalize(node); err != nil {
+			return Stop
+		}
+		return Continue
+	})
+	return err
+}
+
+func (elem *ElementSchema) normalize(node *Node) error {
+	for _, key := range elem.Keys {
+		for _, alias := range key.Aliases {
+			val, ok := node.Keys[alias]
+			if !ok {
+				continue
+			}
+			if _, dup := node.Keys[key.Name]; dup {
+				return schemaError(node, "key %s is an alias of %s which is also set in %s", alias, key.Name, matchSpec(node))
+			}
+			delete(node.Keys, alias)
+			node.Put(key.Name, val)
+		}
+		val, ok := node.Keys[key.Name]
+		switch {
+		case ok:
+			node.Put(key.Name, Coerce(key.Type, val))
+		case key.HasDefault:
+			node.Put(key.Name, key.Default)
+		}
+	}
+	return nil
+}
+
+// Coerce a value into the canonical format of the value type
+// such as yes to true for a bool or 0x10 to 16 for an int
+// a value that cannot be coerced is returned unchanged
+func Coerce(kind, value string) string {
+	switch kind {
+	case TypeBool:
+		switch strings.ToLower(value) {
+		case "yes", "on", "y":
+			return "true"
+		case "no", "off", "n":
+			return "false"
+		}
+		if b, err := strconv.ParseBool(value); err == nil {
+			return strconv.FormatBool(b)
+		}
+	case TypeInt:
+		if i, ok := parseInt(value); ok {
+			return strconv.FormatInt(i, 10)
+		}
+	case TypeFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	}
+	return value
+}
+
+// parseInt reads a decimal int, or hex with an explicit 0x prefix
+// a leading zero is decimal so zero padded values keep their value, and
+// underscores may separate digits
+func parseInt(value string) (int64, bool) {
+	digits, sign := value, ""
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		sign, digits = digits[:1], digits[1:]
+	}
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' && (digits[1] == 'x' || digits[1] == 'X') {
+		base, digits = 16, digits[2:]
+	}
+	if strings.HasPrefix(digits, "_") || strings.HasSuffix(digits, "_") || strings.Contains(digits, "__") {
+		return 0, false
+	}
+	i, err := strconv.ParseInt(sign+strings.ReplaceAll(digits, "_", ""), base, 64)
+	return i, err == nil
+}
@@ -1,11 +1,16 @@
 package brief_test
 
 import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestXMLOut(t *testing.T) {
@@ -19,3 +24,126 @@ func TestXMLOut(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestXMLEscape(t *testing.T) {
+	text := "expr op:\"<&>\" `a < b && c > \"d\"`\n    sub `x&y`\n"
+	nodes, err := brief.Decode(strings.NewReader(text), "tests")
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, nodes[0].WriteXML(&out))
+	var doc struct {
+		Op      string `xml:"op,attr"`
+		Content string `xml:",chardata"`
+		Sub     string `xml:"sub"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(out.String()), &doc), out.String())
+	assert.Equal(t, "<&>", doc.Op)
+	assert.Contains(t, doc.Content, `a < b && c > "d"`)
+	assert.Equal(t, "x&y", doc.Sub)
+}
+
+func TestXMLNames(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader("field:title name:other size:2\n"), "tests")
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, nodes[0].WriteXML(&out))
+	var doc struct {
+		Name string `xml:"name,attr"`
+		Size string `xml:"size,attr"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(out.String()), &doc), out.String())
+	assert.Equal(t, "title", doc.Name)
+	assert.Equal(t, "2", doc.Size)
+
+	nodes, err = brief.Decode(strings.NewReader("field name:only\n"), "tests")
+	require.NoError(t, err)
+	out.Reset()
+	require.NoError(t, nodes[0].WriteXML(&out))
+	assert.Contains(t, out.String(), `name="only"`)
+
+	// nodes built in Go are not limited to brief identifiers
+	bad := brief.NewNode("a<b", 0)
+	assert.Error(t, bad.WriteXML(&out))
+	bad = brief.NewNode("field", 0)
+	bad.Body = append(bad.Body, brief.NewNode("sub", 1))
+	bad.Body[0].Keys["2fa"] = "on"
+	assert.Error(t, bad.WriteXML(&out))
+
+	schema := &brief.Schema{Elements: []*brief.ElementSchema{{Type: "cmd", Root: true, Keys: []*brief.KeySchema{{Name: "2fa"}}}}}
+	assert.Error(t, schema.WriteXSD(&out))
+	schema.Elements[0].Keys[0].Name = "mfa"
+	schema.Elements[0].Type = "a<b"
+	assert.Error(t, schema.WriteXSD(&out))
+}
+
+func TestJSONOut(t *testing.T) {
+	nodes, err := brief.DecodeFile("tests/cli.brief")
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, nodes[0].WriteJSON(&out))
+	t.Logf("\n%s", out.String())
+	var doc struct {
+		Type string
+		Name string
+		Keys map[string]string
+		Body []struct {
+			Type string
+			Name string
+			Body []json.RawMessage
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &doc))
+	assert.Equal(t, "cli", doc.Type)
+	assert.Equal(t, "tool", doc.Name)
+	assert.Equal(t, "1.2", doc.Keys["version"])
+	require.Len(t, doc.Body, 2)
+	assert.Equal(t, "run", doc.Body[0].Name)
+	assert.Len(t, doc.Body[0].Body, 2)
+}
+
+func TestXSDOut(t *testing.T) {
+	schema, err := brief.LoadSchema("tests/cli.schema.brief")
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, schema.WriteXSD(&out))
+	xsd := out.String()
+	t.Logf("\n%s", xsd)
+	dec := xml.NewDecoder(strings.NewReader(xsd))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "xsd is not well formed")
+	}
+	for _, expect := range []string{
+		`<xs:element name="cli" type="cliType"/>`,
+		`<xs:element name="command" type="commandType" minOccurs="1" maxOccurs="unbounded"/>`,
+		`<xs:element name="flag" type="flagType" minOccurs="0" maxOccurs="2"/>`,
+		`<xs:attribute name="name" type="xs:NCName" use="required"/>`,
+		`<xs:attribute name="hidden" type="xs:boolean"/>`,
+		`<xs:attribute name="short" type="xs:NCName" use="required"/>`,
+	} {
+		assert.Contains(t, xsd, expect)
+	}
+	assert.NotContains(t, xsd, `<xs:element name="command" type="commandType"/>`, "command is not a root")
+}
+
+func TestJSONSchemaOut(t *testing.T) {
+	schema, err := brief.LoadSchema("tests/cli.schema.brief")
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, schema.WriteJSONSchema(&out))
+	t.Logf("\n%s", out.String())
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &doc))
+	assert.Equal(t, "cli", doc["title"])
+	defs := doc["$defs"].(map[string]interface{})
+	require.Len(t, defs, 3)
+	flag := defs["flag"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"type", "name", "keys"}, flag["required"])
+	cmd := defs["command"].(map[string]interface{})
+	body := cmd["properties"].(map[string]interface{})["body"].(map[string]interface{})
+	rule := body["allOf"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 2.0, rule["maxContains"])
+}
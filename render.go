@@ -0,0 +1,139 @@
+package brief
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+// DefaultEntry template name used by a Renderer
+const DefaultEntry = "main"
+
+// Renderer executes a set of text/templates against brief nodes
+// with the functions of FuncMap.
+// Templates can call data to get extra values:  {{ data "version" }}
+// The Key, Lookup, Slice, Join and Printf methods of Strict nodes fail in
+// the templates it parses or adds, instead of writing a ValueSpec state.
+type Renderer struct {
+	Templates *template.Template
+	Entry     string            // name of the entry template
+	Data      map[string]string // extra values for the data function
+}
+
+// NewRenderer with an empty template set
+func NewRenderer() *Renderer {
+	r := &Renderer{Entry: DefaultEntry, Data: map[string]string{}}
+	r.Templates = template.New("").Funcs(FuncMap()).Funcs(strictFuncs()).Funcs(r.funcs())
+	return r
+}
+
+func (r *Renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"data": func(key string) (string, error) {
+			val, ok := r.Data[key]
+			if !ok {
+				return "", fmt.Errorf("no data %s", key)
+			}
+			return val, nil
+		},
+	}
+}
+
+// ParseDir parses every file in dir as a template named by its file name
+func (r *Renderer) ParseDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no templates found in %s", dir)
+	}
+	sort.Strings(files)
+	if _, err = r.Templates.ParseFiles(files...); err != nil {
+		return err
+	}
+	strictTemplates(r.Templates)
+	return nil
+}
+
+// Parse text as a named template
+func (r *Renderer) Parse(name, text string) error {
+	if _, err := r.Templates.New(name).Parse(text); err != nil {
+		return err
+	}
+	strictTemplates(r.Templates)
+	return nil
+}
+
+// lookup template by name or by name with a .tmpl extension
+func (r *Renderer) lookup(name string) (*template.Template, error) {
+	for _, try := range []string{name, name + ".tmpl"} {
+		if tmpl := r.Templates.Lookup(try); tmpl != nil {
+			return tmpl, nil
+		}
+	}
+	return nil, fmt.Errorf("no template %s", name)
+}
+
+// AddTemplates adds copies of the templates of set, such as the #template
+// partials of a Decoder, a template replaces one of the same name except
+// the entry template
+func (r *Renderer) AddTemplates(set *template.Template) error {
+	if set == nil {
+		return nil
+	}
+	for _, tmpl := range set.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+		if name := tmpl.Name(); name == r.Entry || name == r.Entry+".tmpl" {
+			return fmt.Errorf("template %s replaces the entry template", name)
+		}
+		if _, err := r.Templates.AddParseTree(tmpl.Name(), tmpl.Tree.Copy()); err != nil {
+			return err
+		}
+	}
+	strictTemplates(r.Templates)
+	return nil
+}
+
+// WithTemplates is a renderer for one decoded file, with its #template
+// partials added to a clone of the templates so that they are not seen by
+// the other files
+func (r *Renderer) WithTemplates(set *template.Template) (*Renderer, error) {
+	if set == nil {
+		return r, nil
+	}
+	templates, err := r.Templates.Clone()
+	if err != nil {
+		return nil, err
+	}
+	file := &Renderer{Templates: templates, Entry: r.Entry, Data: r.Data}
+	if err := file.AddTemplates(set); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Render the entry template for the node
+func (r *Renderer) Render(out io.Writer, node *Node) error {
+	return r.RenderTemplate(out, r.Entry, node)
+}
+
+// RenderTemplate executes the named template for the node
+func (r *Renderer) RenderTemplate(out io.Writer, name string, node *Node) error {
+	tmpl, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, node)
+}
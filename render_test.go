@@ -0,0 +1,85 @@
+package brief_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	nodes, err := brief.DecodeFile("tests/cli.brief")
+	require.NoError(t, err)
+	r := brief.NewRenderer()
+	require.NoError(t, r.ParseDir("tests/tmpl"))
+	r.Data["version"] = "v1"
+	var out strings.Builder
+	require.NoError(t, r.Render(&out, nodes[0]))
+	assert.Equal(t, "tool v1\nrun: -v -c\nlist\n", out.String())
+
+	r.Entry = "nothing"
+	assert.Error(t, r.Render(&out, nodes[0]))
+
+	r.Entry = brief.DefaultEntry
+	delete(r.Data, "version")
+	assert.Error(t, r.Render(&out, nodes[0]), "missing data did not fail")
+}
+
+func TestTemplateFeature(t *testing.T) {
+	dec, err := brief.NewFileDecoder("tests/partials.brief")
+	require.NoError(t, err)
+	nodes, err := dec.Decode()
+	require.NoError(t, err)
+	require.NotNil(t, dec.Templates)
+	assert.NotNil(t, dec.Templates.Lookup("help"))
+	assert.NotNil(t, dec.Templates.Lookup("usage"), "partial from include")
+
+	r := brief.NewRenderer()
+	require.NoError(t, r.Parse(brief.DefaultEntry, `{{range .Body}}{{template "help" .}}
+{{end}}`))
+	require.NoError(t, r.AddTemplates(dec.Templates))
+	var out strings.Builder
+	require.NoError(t, r.Render(&out, nodes[0]))
+	assert.Equal(t, "run: run the tool\nlist: no usage\n", out.String())
+
+	_, err = brief.Decode(strings.NewReader("#template bad `{{ .Name `\n"), "")
+	assert.Error(t, err, "invalid template")
+	_, err = brief.Decode(strings.NewReader("#template empty\nelem\n"), "")
+	assert.Error(t, err, "missing content block")
+}
+
+func TestWithTemplates(t *testing.T) {
+	decode := func(text string) *brief.Decoder {
+		dec := brief.NewDecoder(strings.NewReader(text), 4, "")
+		_, err := dec.Decode()
+		require.NoError(t, err)
+		return dec
+	}
+	r := brief.NewRenderer()
+	require.NoError(t, r.Parse(brief.DefaultEntry, `{{template "help" .}}`))
+	require.NoError(t, r.Parse("help", `default help`))
+	node := brief.NewNode("cmd", 0)
+
+	one, err := r.WithTemplates(decode("#template help `one help`\n").Templates)
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, one.Render(&out, node))
+	assert.Equal(t, "one help", out.String())
+
+	// the partials of one file are not seen by the next
+	out.Reset()
+	require.NoError(t, r.Render(&out, node))
+	assert.Equal(t, "default help", out.String())
+	two, err := r.WithTemplates(nil)
+	require.NoError(t, err)
+	out.Reset()
+	require.NoError(t, two.Render(&out, node))
+	assert.Equal(t, "default help", out.String())
+
+	// a partial cannot replace the entry
+	_, err = r.WithTemplates(decode("#template main `replaced`\n").Templates)
+	assert.Error(t, err)
+	assert.Error(t, r.AddTemplates(decode("#template \"main.tmpl\" `replaced`\n").Templates))
+}
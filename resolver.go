@@ -0,0 +1,197 @@
+package brief
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Resolver opens the files of #include features
+// path is the include file name joined to the directory of the including file
+// A file found in an IncludeCache is not opened, it is checked by the
+// Check(path string) error method of the resolver, or opened and closed when
+// the resolver has none.
+type Resolver interface {
+	Open(path string) (io.ReadCloser, error)
+}
+
+// checker is a Resolver that checks a path without opening it
+type checker interface {
+	Check(path string) error
+}
+
+// OSResolver opens any file, set it as the Decoder Resolver to allow
+// includes from outside the directory of the decoded file
+type OSResolver struct{}
+
+// Open the file
+func (OSResolver) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// Check allows any path
+func (OSResolver) Check(path string) error {
+	return nil
+}
+
+// RootResolver confines includes to a root directory
+// absolute paths, ../ and symbolic links that lead outside the root are rejected
+// A Decoder without a Resolver uses one rooted at its directory.
+type RootResolver struct {
+	Root string // absolute with symbolic links evaluated
+}
+
+// NewRootResolver confined to the root directory
+func NewRootResolver(root string) (*RootResolver, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	return &RootResolver{Root: real}, nil
+}
+
+// Open the file if it is in the root directory
+// The path is resolved and checked before it is opened, and the open file is
+// then confirmed to be the file the path resolves to, so a path changed in
+// between is rejected.
+func (r *RootResolver) Open(path string) (io.ReadCloser, error) {
+	real, err := r.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(real)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", path, err)
+	}
+	if err := r.confirm(path, file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// Check the path resolves to a regular file in the root
+func (r *RootResolver) Check(path string) error {
+	_, err := r.resolve(path)
+	return err
+}
+
+// resolve the real path of a regular file in the root
+func (r *RootResolver) resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("include %s: %w", path, err)
+	}
+	if !within(r.Root, real) {
+		return "", fmt.Errorf("include %s is outside the root %s", path, r.Root)
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return "", fmt.Errorf("include %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("include %s is not a regular file", path)
+	}
+	return real, nil
+}
+
+// confirm the open file is still the file the path resolves to in the root
+func (r *RootResolver) confirm(path string, file *os.File) error {
+	real, err := r.resolve(path)
+	if err != nil {
+		return err
+	}
+	opened, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	found, err := os.Stat(real)
+	if err != nil || !os.SameFile(opened, found) {
+		return fmt.Errorf("include %s changed while it was opened", path)
+	}
+	return nil
+}
+
+// FSResolver opens includes from a file system, such as an embed.FS or an
+// in-memory fstest.MapFS.  Dir is the directory of the file system root,
+// decode with a srcdir in Dir.
+type FSResolver struct {
+	FS  fs.FS
+	Dir string
+}
+
+// Open the file below Dir from the file system
+func (r *FSResolver) Open(path string) (io.ReadCloser, error) {
+	rel, err := r.rel(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.FS.Open(rel)
+}
+
+// Check the file is below Dir in the file system
+func (r *FSResolver) Check(path string) error {
+	rel, err := r.rel(path)
+	if err != nil {
+		return err
+	}
+	_, err = fs.Stat(r.FS, rel)
+	return err
+}
+
+// rel is the slash separated path of the file in the file system
+func (r *FSResolver) rel(path string) (string, error) {
+	dir, err := filepath.Abs(r.Dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || !within(dir, abs) {
+		return "", fmt.Errorf("include %s is outside %s", path, r.Dir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// resolver of the decoder, a RootResolver at the decoder directory when it
+// has none, which is kept so that includes share the root
+func (dec *Decoder) resolver() (Resolver, error) {
+	if dec.Resolver == nil {
+		root, err := NewRootResolver(dec.Dir)
+		if err != nil {
+			return nil, err
+		}
+		dec.Resolver = root
+	}
+	return dec.Resolver, nil
+}
+
+// allow the include of a cached file, which is checked by the resolver
+// instead of being opened
+func (dec *Decoder) allow(filename string) error {
+	resolver, err := dec.resolver()
+	if err != nil {
+		return err
+	}
+	if check, ok := resolver.(checker); ok {
+		return check.Check(filename)
+	}
+	file, err := resolver.Open(filename)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,88 @@
+package brief_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeWith(resolver brief.Resolver, dir, text string) ([]*brief.Node, error) {
+	dec := brief.NewDecoder(strings.NewReader(text), 4, dir)
+	dec.Resolver = resolver
+	return dec.Decode()
+}
+
+func TestRootResolver(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "part.brief"), []byte("part:in\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.brief"), []byte("secret\n"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.brief"), filepath.Join(root, "escape.brief")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "part.brief"), filepath.Join(root, "alias.brief")))
+	resolver, err := brief.NewRootResolver(root)
+	require.NoError(t, err)
+
+	tests := []struct {
+		Include string
+		OK      bool
+	}{
+		{"part.brief", true},
+		{"alias.brief", true},
+		{"escape.brief", false},
+		{"../" + filepath.Base(outside) + "/secret.brief", false},
+		{filepath.Join(outside, "secret.brief"), false},
+		{"missing.brief", false},
+	}
+	for _, test := range tests {
+		nodes, err := decodeWith(resolver, root, "#include `"+test.Include+"`\n")
+		if !test.OK {
+			assert.Error(t, err, test.Include)
+			continue
+		}
+		require.NoError(t, err, test.Include)
+		require.Len(t, nodes, 1)
+		assert.Equal(t, "in", nodes[0].Name)
+	}
+
+	// the default is confined to the decoder directory
+	_, err = decodeWith(nil, root, "#include `part.brief`\n")
+	assert.NoError(t, err)
+	_, err = decodeWith(nil, root, "#include `escape.brief`\n")
+	assert.Error(t, err)
+	_, err = decodeWith(nil, root, "#include `"+filepath.Join(outside, "secret.brief")+"`\n")
+	assert.Error(t, err)
+	// nested includes keep the root of the first file
+	require.NoError(t, os.Mkdir(filepath.Join(root, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "up.brief"), []byte("#include `../part.brief`\n"), 0644))
+	nodes, err := decodeWith(nil, root, "#include `sub/up.brief`\n")
+	require.NoError(t, err)
+	assert.Equal(t, "in", nodes[0].Name)
+	_, err = decodeWith(nil, filepath.Join(root, "sub"), "#include `../part.brief`\n")
+	assert.Error(t, err)
+
+	// OSResolver opts out and reads any file
+	_, err = decodeWith(brief.OSResolver{}, root, "#include `escape.brief`\n")
+	assert.NoError(t, err)
+}
+
+func TestFSResolver(t *testing.T) {
+	files := fstest.MapFS{
+		"inc/part.brief": {Data: []byte("part\n    #include `../shared.brief`\n")},
+		"shared.brief":   {Data: []byte("shared:yes\n")},
+	}
+	resolver := &brief.FSResolver{FS: files, Dir: "/virtual"}
+	nodes, err := decodeWith(resolver, "/virtual", "#include `inc/part.brief`\n")
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.Len(t, nodes[0].Body, 1)
+	assert.Equal(t, "yes", nodes[0].Body[0].Name)
+
+	_, err = decodeWith(resolver, "/virtual", "#include `../etc/passwd`\n")
+	assert.Error(t, err)
+}
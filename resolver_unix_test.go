@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package brief_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootResolverFIFO(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, syscall.Mkfifo(filepath.Join(outside, "pipe"), 0644))
+	require.NoError(t, syscall.Mkfifo(filepath.Join(root, "pipe.brief"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "pipe"), filepath.Join(root, "escape.brief")))
+	for _, include := range []string{"escape.brief", "pipe.brief"} {
+		done := make(chan error, 1)
+		go func() {
+			_, err := decodeWith(nil, root, "#include `"+include+"`\n")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			assert.Error(t, err, include)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s was opened", include)
+		}
+	}
+}
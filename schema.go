@@ -0,0 +1,487 @@
+package brief
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+)
+
+// Schema value types
+const (
+	TypeString = "string"
+	TypeIdent  = "ident"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+)
+
+// Schema describes the element types, keys and nesting of brief documents
+// A schema is written in brief:
+//
+//	schema:cli
+//	    element:command root:true
+//	        name required:true
+//	        key:hidden type:bool default:false alias:hide
+//	        child:flag min:0
+type Schema struct {
+	Name     string
+	Elements []*ElementSchema
+}
+
+// ElementSchema describes an element type
+type ElementSchema struct {
+	Type     string
+	Root     bool       // may be a top-level element
+	Open     bool       // allows keys that are not declared
+	Name     *KeySchema // rules for the element name, nil if any name is allowed
+	Keys     []*KeySchema
+	Children []*ChildSchema
+	Parents  []string // element types seen containing this one, when inferred
+	Count    int      // number of elements seen, when inferred
+	Node     *Node    // source of the element schema
+}
+
+// KeySchema describes a key of an element
+type KeySchema struct {
+	Name, Type string
+	Required   bool
+	Default    string // value of a missing key when HasDefault
+	HasDefault bool
+	Aliases    []string // other names normalised to this key
+	Count      int      // number of values seen, when inferred
+	Node       *Node
+}
+
+// ChildSchema describes the cardinality of a child element type
+type ChildSchema struct {
+	Type     string
+	Min, Max int // a negative Max is unbounded
+	Node     *Node
+}
+
+// LoadSchema from a brief file
+func LoadSchema(filename string) (*Schema, error) {
+	nodes, err := DecodeFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(nodes)
+}
+
+// ParseSchema from decoded brief nodes
+// the nodes are schema elements or element nodes
+func ParseSchema(nodes []*Node) (*Schema, error) {
+	schema := &Schema{}
+	if err := schema.parse(nodes); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// parse adds the elements in nodes to the schema
+func (schema *Schema) parse(nodes []*Node) error {
+	for _, node := range nodes {
+		switch node.Type {
+		case "schema":
+			if len(schema.Name) == 0 {
+				schema.Name = node.Name
+			}
+			for _, sub := range node.Body {
+				if err := schema.parseElement(sub); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := schema.parseElement(node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func schemaError(node *Node, format string, args ...interface{}) error {
+	return &Violation{Pos: node.Pos, Node: node, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (schema *Schema) parseElement(node *Node) error {
+	if node.Type != "element" {
+		return schemaError(node, "schema expects element not %s", node.Type)
+	}
+	if !node.HasName() {
+		return schemaError(node, "element requires a type name")
+	}
+	if schema.Element(node.Name) != nil {
+		return schemaError(node, "element %s is declared twice", node.Name)
+	}
+	elem := &ElementSchema{Type: node.Name, Node: node}
+	var err error
+	if elem.Root, err = schemaBool(node, "root"); err != nil {
+		return err
+	}
+	if elem.Open, err = schemaBool(node, "open"); err != nil {
+		return err
+	}
+	if elem.Count, err = schemaCount(node, "count"); err != nil {
+		return err
+	}
+	elem.Parents = strings.Fields(node.Keys["parents"])
+	for _, sub := range node.Body {
+		switch sub.Type {
+		case "name":
+			if elem.Name, err = parseKeySchema(sub, "name"); err != nil {
+				return err
+			}
+		case "key":
+			if !sub.HasName() {
+				return schemaError(sub, "key requires a name")
+			}
+			if elem.Key(sub.Name) != nil {
+				return schemaError(sub, "key %s is declared twice in %s", sub.Name, elem.Type)
+			}
+			key, err := parseKeySchema(sub, sub.Name)
+			if err != nil {
+				return err
+			}
+			elem.Keys = append(elem.Keys, key)
+		case "child":
+			if !sub.HasName() {
+				return schemaError(sub, "child requires an element type")
+			}
+			if elem.Child(sub.Name) != nil {
+				return schemaError(sub, "child %s is declared twice in %s", sub.Name, elem.Type)
+			}
+			child, err := parseChildSchema(sub)
+			if err != nil {
+				return err
+			}
+			elem.Children = append(elem.Children, child)
+		default:
+			return schemaError(sub, "unknown element schema %s", sub.Type)
+		}
+	}
+	schema.Elements = append(schema.Elements, elem)
+	return nil
+}
+
+func parseKeySchema(node *Node, name string) (*KeySchema, error) {
+	key := &KeySchema{Name: name, Type: TypeString, Node: node}
+	if kind, ok := node.Keys["type"]; ok {
+		switch kind {
+		case TypeString, TypeIdent, TypeInt, TypeFloat, TypeBool:
+			key.Type = kind
+		default:
+			return nil, schemaError(node, "unknown value type %s", kind)
+		}
+	}
+	if val, ok := node.Keys["default"]; ok {
+		key.Default = val
+		key.HasDefault = true
+	}
+	key.Aliases = strings.Fields(node.Keys["alias"])
+	var err error
+	if key.Count, err = schemaCount(node, "count"); err != nil {
+		return nil, err
+	}
+	key.Required, err = schemaBool(node, "required")
+	return key, err
+}
+
+func parseChildSchema(node *Node) (*ChildSchema, error) {
+	child := &ChildSchema{Type: node.Name, Max: -1, Node: node}
+	var err error
+	if child.Min, err = schemaCount(node, "min"); err != nil {
+		return nil, err
+	}
+	if _, ok := node.Keys["max"]; ok {
+		if child.Max, err = schemaCount(node, "max"); err != nil {
+			return nil, err
+		}
+	}
+	if child.Max >= 0 && child.Min > child.Max {
+		return nil, schemaError(node, "min %d is more than max %d", child.Min, child.Max)
+	}
+	return child, nil
+}
+
+func schemaBool(node *Node, key string) (bool, error) {
+	val, ok := node.Keys[key]
+	if !ok {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, schemaError(node, "%s must be true or false not %q", key, val)
+	}
+	return b, nil
+}
+
+func schemaCount(node *Node, key string) (int, error) {
+	val, ok := node.Keys[key]
+	if !ok {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(val)
+	if err != nil || count < 0 {
+		return 0, schemaError(node, "%s must be a count not %q", key, val)
+	}
+	return count, nil
+}
+
+// Element schema for an element type or nil
+func (schema *Schema) Element(elemType string) *ElementSchema {
+	for _, elem := range schema.Elements {
+		if elem.Type == elemType {
+			return elem
+		}
+	}
+	return nil
+}
+
+// hasRoots true if any element is marked as a root
+func (schema *Schema) hasRoots() bool {
+	for _, elem := range schema.Elements {
+		if elem.Root {
+			return true
+		}
+	}
+	return false
+}
+
+// Key schema by name or nil
+func (elem *ElementSchema) Key(name string) *KeySchema {
+	for _, key := range elem.Keys {
+		if key.Name == name {
+			return key
+		}
+	}
+	return nil
+}
+
+// Child schema by element type or nil
+func (elem *ElementSchema) Child(elemType string) *ChildSchema {
+	for _, child := range elem.Children {
+		if child.Type == elemType {
+			return child
+		}
+	}
+	return nil
+}
+
+// Valid true if value is formatted for the key type
+func (key *KeySchema) Valid(value string) bool {
+	return ValidType(key.Type, value)
+}
+
+// ValidType true if value is formatted for the value type
+func ValidType(kind, value string) bool {
+	var err error
+	switch kind {
+	case TypeBool:
+		_, err = strconv.ParseBool(value)
+	case TypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case TypeFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case TypeIdent:
+		return isIdent(value)
+	}
+	return err == nil
+}
+
+// isIdent true if value is a brief identifier
+func isIdent(value string) bool {
+	var s scanner.Scanner
+	s.Init(strings.NewReader(value))
+	s.Error = func(*scanner.Scanner, string) {}
+	return s.Scan() == scanner.Ident && s.TokenText() == value
+}
+
+// Node of the schema written in the schema language
+func (schema *Schema) Node() *Node {
+	root := NewNode("schema", 0)
+	root.Name = schema.Name
+	for _, elem := range schema.Elements {
+		node := root.add("element", elem.Type)
+		putBool(node, "root", elem.Root)
+		putBool(node, "open", elem.Open)
+		if len(elem.Parents) > 0 {
+			node.Put("parents", strings.Join(elem.Parents, " "))
+		}
+		putCount(node, "count", elem.Count)
+		if elem.Name != nil {
+			elem.Name.put(node.add("name", ""))
+		}
+		for _, key := range elem.Keys {
+			key.put(node.add("key", key.Name))
+		}
+		for _, child := range elem.Children {
+			sub := node.add("child", child.Type)
+			putCount(sub, "min", child.Min)
+			if child.Max >= 0 {
+				sub.Put("max", strconv.Itoa(child.Max))
+			}
+		}
+	}
+	return root
+}
+
+// Encode the schema in brief format
+func (schema *Schema) Encode() []byte {
+	return schema.Node().Encode()
+}
+
+func (key *KeySchema) put(node *Node) {
+	if key.Type != TypeString {
+		node.Put("type", key.Type)
+	}
+	putBool(node, "required", key.Required)
+	if key.HasDefault {
+		node.Put("default", key.Default)
+	}
+	if len(key.Aliases) > 0 {
+		node.Put("alias", strings.Join(key.Aliases, " "))
+	}
+	putCount(node, "count", key.Count)
+}
+
+// add a child node to the body
+func (node *Node) add(elemType, name string) *Node {
+	sub := NewNode(elemType, node.Indent+TabCount)
+	sub.Name = name
+	sub.Parent = node
+	node.Body = append(node.Body, sub)
+	return sub
+}
+
+func putBool(node *Node, key string, val bool) {
+	if val {
+		node.Put(key, "true")
+	}
+}
+
+func putCount(node *Node, key string, val int) {
+	if val > 0 {
+		node.Put(key, strconv.Itoa(val))
+	}
+}
+
+// Violation of a schema by a node
+type Violation struct {
+	Pos  scanner.Position
+	Node *Node
+	Msg  string
+}
+
+func (v *Violation) Error() string {
+	if v.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", v.Pos, v.Msg)
+	}
+	return v.Msg
+}
+
+// Violations of a schema
+type Violations []*Violation
+
+func (vs Violations) Error() string {
+	msgs := make([]string, len(vs))
+	for i, v := range vs {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns the violations as an error or nil if there are none
+func (vs Violations) Err() error {
+	if len(vs) == 0 {
+		return nil
+	}
+	return vs
+}
+
+func (vs *Violations) add(node *Node, format string, args ...interface{}) {
+	*vs = append(*vs, &Violation{Pos: node.Pos, Node: node, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Validate nodes against the schema
+// reports unknown element types, missing and unknown keys, key values
+// of the wrong type, missing names and child cardinality
+func Validate(nodes []*Node, schema *Schema) Violations {
+	var vs Violations
+	roots := schema.hasRoots()
+	for _, node := range nodes {
+		elem := schema.Element(node.Type)
+		if elem == nil {
+			vs.add(node, "unknown element type %s", node.Type)
+			continue
+		}
+		if roots && !elem.Root {
+			vs.add(node, "%s is not a root element", node.Type)
+		}
+		vs.validate(node, elem, schema)
+	}
+	return vs
+}
+
+func (vs *Violations) validate(node *Node, elem *ElementSchema, schema *Schema) {
+	spec := matchSpec(node)
+	if elem.Name != nil {
+		switch {
+		case !node.HasName():
+			if elem.Name.Required {
+				vs.add(node, "%s requires a name", node.Type)
+			}
+		case !elem.Name.Valid(node.Name):
+			vs.add(node, "name %q of %s is not %s %s", node.Name, node.Type, article(elem.Name.Type), elem.Name.Type)
+		}
+	}
+	for _, key := range elem.Keys {
+		val, ok := node.Keys[key.Name]
+		switch {
+		case !ok:
+			if key.Required {
+				vs.add(node, "missing key %s in %s", key.Name, spec)
+			}
+		case !key.Valid(val):
+			vs.add(node, "key %s value %q in %s is not %s %s", key.Name, val, spec, article(key.Type), key.Type)
+		}
+	}
+	if !elem.Open {
+		for _, name := range sortedKeys(node.Keys) {
+			if elem.Key(name) == nil {
+				vs.add(node, "unknown key %s in %s", name, spec)
+			}
+		}
+	}
+	counts := map[string]int{}
+	for _, sub := range node.Body {
+		if elem.Child(sub.Type) == nil {
+			vs.add(sub, "unexpected %s in %s", sub.Type, spec)
+			continue
+		}
+		counts[sub.Type]++
+		subElem := schema.Element(sub.Type)
+		if subElem == nil {
+			vs.add(sub, "unknown element type %s", sub.Type)
+			continue
+		}
+		vs.validate(sub, subElem, schema)
+	}
+	for _, child := range elem.Children {
+		count := counts[child.Type]
+		if count < child.Min {
+			vs.add(node, "%s requires at least %d %s, found %d", spec, child.Min, child.Type, count)
+		}
+		if child.Max >= 0 && count > child.Max {
+			vs.add(node, "%s allows at most %d %s, found %d", spec, child.Max, child.Type, count)
+		}
+	}
+}
+
+func article(kind string) string {
+	if strings.ContainsRune("aeiou", rune(kind[0])) {
+		return "an"
+	}
+	return "a"
+}
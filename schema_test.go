@@ -0,0 +1,149 @@
+package brief_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchema(t *testing.T) {
+	schema, err := brief.LoadSchema("tests/cli.schema.brief")
+	require.NoError(t, err)
+	assert.Equal(t, "cli", schema.Name)
+	require.Len(t, schema.Elements, 3)
+	cmd := schema.Element("command")
+	require.NotNil(t, cmd)
+	assert.True(t, cmd.Name.Required)
+	assert.Equal(t, brief.TypeBool, cmd.Key("hidden").Type)
+	assert.Equal(t, brief.TypeString, cmd.Key("usage").Type)
+	assert.Equal(t, 2, cmd.Child("flag").Max)
+	assert.Equal(t, 1, schema.Element("cli").Child("command").Min)
+	assert.Equal(t, -1, schema.Element("cli").Child("command").Max)
+}
+
+func TestBadSchema(t *testing.T) {
+	tests := []string{
+		"schema\n    elem:x",
+		"element",
+		"element:x\n    key:y type:bogus",
+		"element:x\n    child:y min:3 max:1",
+		"element:x root:maybe",
+		"element:x\nelement:x",
+	}
+	for i, test := range tests {
+		nodes, err := brief.Decode(strings.NewReader(test), "tests")
+		require.NoError(t, err)
+		_, err = brief.ParseSchema(nodes)
+		assert.Error(t, err, "%d> %q", i, test)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := brief.LoadSchema("tests/cli.schema.brief")
+	require.NoError(t, err)
+
+	nodes, err := brief.DecodeFile("tests/cli.brief")
+	require.NoError(t, err)
+	assert.NoError(t, brief.Validate(nodes, schema).Err())
+
+	nodes, err = brief.DecodeFile("tests/badcli.brief")
+	require.NoError(t, err)
+	vs := brief.Validate(nodes, schema)
+	expect := []string{
+		`tests/badcli.brief:1:1: key version value "one" in cli:tool is not a float`,
+		`tests/badcli.brief:2:5: unknown key color in command:run`,
+		`tests/badcli.brief:3:9: missing key short in flag:verbose`,
+		`tests/badcli.brief:4:9: key count value "many" in flag:count is not an int`,
+		`tests/badcli.brief:6:9: unexpected option in command:run`,
+		`tests/badcli.brief:2:5: command:run allows at most 2 flag, found 3`,
+		`tests/badcli.brief:7:5: command requires a name`,
+		`tests/badcli.brief:7:5: key hidden value "maybe" in command is not a bool`,
+		`tests/badcli.brief:8:1: command is not a root element`,
+		`tests/badcli.brief:9:1: unknown element type widget`,
+	}
+	msgs := make([]string, len(vs))
+	for i, v := range vs {
+		msgs[i] = v.Error()
+	}
+	assert.Equal(t, expect, msgs)
+}
+
+func TestDefaults(t *testing.T) {
+	dec, err := brief.NewFileDecoder("tests/defaults.brief")
+	require.NoError(t, err)
+	nodes, err := dec.Decode()
+	require.NoError(t, err)
+	require.NotNil(t, dec.Defaults)
+
+	run := nodes[0].Child("command:run")
+	assert.Equal(t, map[string]string{"description": "run it", "hidden": "true", "retries": "16"}, run.Keys)
+	list := nodes[0].Child("command:list")
+	assert.Equal(t, map[string]string{"description": "list them", "hidden": "false", "retries": "3"}, list.Keys)
+	assert.Empty(t, nodes[0].Keys, "defaults applied to other element types")
+}
+
+func TestDefaultsAliasConflict(t *testing.T) {
+	text := "#defaults `element:cmd\n    key:description alias:desc`\ncmd desc:one description:two"
+	_, err := brief.Decode(strings.NewReader(text), "tests")
+	assert.Error(t, err)
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		Kind, Value, Result string
+	}{
+		{brief.TypeBool, "yes", "true"},
+		{brief.TypeBool, "OFF", "false"},
+		{brief.TypeBool, "T", "true"},
+		{brief.TypeBool, "maybe", "maybe"},
+		{brief.TypeInt, "0x1f", "31"},
+		{brief.TypeInt, "1_000", "1000"},
+		{brief.TypeInt, "ten", "ten"},
+		{brief.TypeInt, "010", "10"},
+		{brief.TypeInt, "0755", "755"},
+		{brief.TypeInt, "-007", "-7"},
+		{brief.TypeInt, "-0x10", "-16"},
+		{brief.TypeInt, "0b101", "0b101"},
+		{brief.TypeInt, "_1", "_1"},
+		{brief.TypeFloat, "1.50", "1.5"},
+		{brief.TypeFloat, "2e3", "2000"},
+		{brief.TypeString, "0x1f", "0x1f"},
+	}
+	for i, test := range tests {
+		if res := brief.Coerce(test.Kind, test.Value); res != test.Result {
+			t.Errorf("%d> %s %q failed %q != %q", i, test.Kind, test.Value, test.Result, res)
+		}
+	}
+}
+
+func TestDefaultsInclude(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cmd.brief"), []byte("element:cmd\n    key:retries default:3\n"), 0644))
+	text := "top\n#defaults #|\n#include `cmd.brief`\n|#\ncmd:run\n"
+	dec := brief.NewDecoder(strings.NewReader(text), 4, dir)
+	nodes, err := dec.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, "3", nodes[1].Keys["retries"])
+	require.Len(t, dec.Includes, 1)
+	assert.Equal(t, filepath.Join(dir, "cmd.brief"), dec.Includes[0].Path)
+	assert.Equal(t, 3, dec.Includes[0].Pos.Line)
+
+	// the includes of the block use the resolver of the decoder
+	dec = brief.NewDecoder(strings.NewReader(text), 4, dir)
+	dec.Resolver = &brief.FSResolver{FS: fstest.MapFS{}, Dir: dir}
+	_, err = dec.Decode()
+	assert.Error(t, err)
+
+	// and count against its limits
+	dec = brief.NewDecoder(strings.NewReader("#include `cmd.brief`\n"+text), 4, dir)
+	dec.Limits = brief.Limits{MaxIncludes: 1}
+	_, err = dec.Decode()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MaxIncludes")
+}
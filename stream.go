@@ -0,0 +1,110 @@
+package brief
+
+import (
+	"io"
+	"text/scanner"
+)
+
+// Event of a streaming decode, one of StartElement, Key, Content or EndElement
+type Event interface{}
+
+// StartElement begins an element, its keys, content and body follow
+type StartElement struct {
+	Type, Name string
+	Depth      int // zero for a root element
+	Pos        scanner.Position
+}
+
+// Key value of the current element
+type Key struct {
+	Name, Value string
+}
+
+// Content of the current element
+type Content struct {
+	Text string
+}
+
+// EndElement ends the element after its body
+type EndElement struct {
+	Type, Name string
+	Depth      int
+}
+
+// NextEvent decodes the next event, it returns io.EOF after the last event
+// Only the open elements are kept in memory, so a document of any size can
+// be filtered or processed without decoding every Node.  The events of a
+// line are returned once the line is complete.  Included files are decoded
+// and then returned as events, and #defaults are not applied to a stream.
+// Do not mix NextEvent with Decode on one Decoder.
+//
+//	for {
+//		event, err := dec.NextEvent()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//		switch e := event.(type) {
+//		case brief.StartElement:
+//		case brief.Key:
+//		}
+//	}
+func (dec *Decoder) NextEvent() (Event, error) {
+	if !dec.streaming {
+		dec.streaming = true
+		dec.State = KeyEmpty
+	}
+	for dec.ready == 0 {
+		if err := dec.failed(); err != nil {
+			return nil, err
+		}
+		if !dec.next() {
+			dec.checkBranches()
+			if err := dec.failed(); err != nil {
+				return nil, err
+			}
+			if dec.start == nil && len(dec.Nesting) == 0 && len(dec.events) == 0 {
+				return nil, io.EOF
+			}
+			dec.start = nil
+			dec.findParent(-1) // end all open elements
+			dec.ready = len(dec.events)
+			continue
+		}
+		if dec.Text.LineStart {
+			dec.ready = len(dec.events)
+		}
+		if err := dec.step(); err != nil {
+			return nil, err
+		}
+	}
+	event := dec.events[0]
+	dec.events = dec.events[1:]
+	dec.ready--
+	if start, ok := event.(*StartElement); ok {
+		return *start, nil
+	}
+	return event, nil
+}
+
+// emit an event when streaming
+func (dec *Decoder) emit(event Event) {
+	if dec.streaming {
+		dec.events = append(dec.events, event)
+	}
+}
+
+// emitNode emits the events of a decoded node and its body
+func (dec *Decoder) emitNode(node *Node, depth int) {
+	dec.emit(&StartElement{Type: node.Type, Name: node.Name, Depth: depth, Pos: node.Pos})
+	for _, key := range sortedKeys(node.Keys) {
+		dec.emit(Key{Name: key, Value: node.Keys[key]})
+	}
+	if node.HasContent() {
+		dec.emit(Content{Text: node.Content})
+	}
+	for _, sub := range node.Body {
+		dec.emitNode(sub, depth+1)
+	}
+	dec.emit(EndElement{Type: node.Type, Name: node.Name, Depth: depth})
+}
@@ -0,0 +1,114 @@
+package brief_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func events(t *testing.T, dec *brief.Decoder) []brief.Event {
+	result := make([]brief.Event, 0)
+	for {
+		event, err := dec.NextEvent()
+		if err == io.EOF {
+			return result
+		}
+		require.NoError(t, err)
+		result = append(result, event)
+	}
+}
+
+func TestStream(t *testing.T) {
+	dec, err := brief.NewFileDecoder("tests/cli.brief")
+	require.NoError(t, err)
+	var out strings.Builder
+	for _, event := range events(t, dec) {
+		switch e := event.(type) {
+		case brief.StartElement:
+			fmt.Fprintf(&out, "%d<%s:%s@%d ", e.Depth, e.Type, e.Name, e.Pos.Line)
+		case brief.Key:
+			fmt.Fprintf(&out, "%s=%s ", e.Name, e.Value)
+		case brief.Content:
+			fmt.Fprintf(&out, "`%s` ", e.Text)
+		case brief.EndElement:
+			fmt.Fprintf(&out, "%d>%s ", e.Depth, e.Type)
+		}
+	}
+	assert.Equal(t, "0<cli:tool@1 version=1.2 "+
+		"1<command:run@2 usage=run the tool "+
+		"2<flag:verbose@3 short=v 2>flag "+
+		"2<flag:count@4 short=c count=3 2>flag 1>command "+
+		"1<command:list@5 hidden=true 1>command 0>cli ", out.String())
+}
+
+// build nodes from the events of a stream
+func build(t *testing.T, dec *brief.Decoder) []*brief.Node {
+	roots := make([]*brief.Node, 0)
+	open := make([]*brief.Node, 0)
+	for _, event := range events(t, dec) {
+		switch e := event.(type) {
+		case brief.StartElement:
+			node := brief.NewNode(e.Type, e.Depth*4)
+			node.Name = e.Name
+			if e.Depth == 0 {
+				roots = append(roots, node)
+			} else {
+				parent := open[len(open)-1]
+				node.Parent = parent
+				parent.Body = append(parent.Body, node)
+			}
+			open = append(open, node)
+		case brief.Key:
+			open[len(open)-1].Put(e.Name, e.Value)
+		case brief.Content:
+			open[len(open)-1].Content = e.Text
+		case brief.EndElement:
+			require.Equal(t, e.Type, open[len(open)-1].Type)
+			open = open[:len(open)-1]
+		}
+	}
+	require.Empty(t, open, "elements not ended")
+	return roots
+}
+
+func encode(nodes []*brief.Node) string {
+	var out strings.Builder
+	for _, node := range nodes {
+		node.Walk(func(n *brief.Node, depth int) brief.WalkAction {
+			n.Indent = depth * 4
+			return brief.Continue
+		})
+		out.Write(node.Encode())
+	}
+	return out.String()
+}
+
+func TestStreamMatchesDecode(t *testing.T) {
+	for _, file := range []string{"test1", "test2", "test3", "test4", "test5", "base", "linked"} {
+		text, err := os.ReadFile("tests/" + file + ".brief")
+		require.NoError(t, err)
+		nodes, err := brief.NewDecoder(strings.NewReader(string(text)), 4, "tests").Decode()
+		require.NoError(t, err, file)
+		streamed := build(t, brief.NewDecoder(strings.NewReader(string(text)), 4, "tests"))
+		assert.Equal(t, encode(nodes), encode(streamed), file)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	dec := brief.NewDecoder(strings.NewReader("elem:foo\n    sub::bar\n"), 4, "")
+	event, err := dec.NextEvent()
+	require.NoError(t, err)
+	assert.Equal(t, "elem", event.(brief.StartElement).Type)
+	_, err = dec.NextEvent()
+	assert.Error(t, err)
+
+	dec = brief.NewDecoder(strings.NewReader(""), 4, "")
+	_, err = dec.NextEvent()
+	assert.Equal(t, io.EOF, err)
+}
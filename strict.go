@@ -0,0 +1,168 @@
+package brief
+
+import (
+	"fmt"
+	"text/template"
+	"text/template/parse"
+)
+
+// strictCalls maps the Node methods that return a ValueSpec state to the
+// funcs a Renderer calls instead, which fail when the node is Strict
+var strictCalls = map[string]string{
+	"Key":    "strictKey",
+	"Lookup": "strictLookup",
+	"Slice":  "strictSlice",
+	"Join":   "strictJoin",
+	"Printf": "strictPrintf",
+}
+
+// strictFuncs take the node first, as the receiver of the method they replace
+func strictFuncs() template.FuncMap {
+	return template.FuncMap{
+		"strictKey": func(node *Node, name string) (string, error) {
+			return keyValue(name, node)
+		},
+		"strictLookup": func(node *Node, spec string) (string, error) {
+			found, err := node.lookups([]string{spec})
+			if err != nil {
+				return "", err
+			}
+			return found[0], nil
+		},
+		"strictSlice": func(node *Node, specs ...string) ([]string, error) {
+			return node.lookups(specs)
+		},
+		"strictJoin": func(node *Node, sep string, specs ...string) (string, error) {
+			return joinValues(sep, node, specs...)
+		},
+		"strictPrintf": func(node *Node, format string, specs ...string) (string, error) {
+			found, err := node.lookups(specs)
+			if err != nil {
+				return "", err
+			}
+			args := make([]interface{}, len(found))
+			for i, val := range found {
+				args[i] = val
+			}
+			return fmt.Sprintf(format, args...), nil
+		},
+	}
+}
+
+// lookups of the specs, an error for the first spec without a value when
+// the node is Strict
+func (node *Node) lookups(specs []string) ([]string, error) {
+	found := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if !node.Strict {
+			found = append(found, node.Lookup(spec))
+			continue
+		}
+		val, err := node.LookupErr(spec)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, val)
+	}
+	return found, nil
+}
+
+// strictTemplates rewrites the calls of the strictCalls methods in the set,
+// {{ .Parent.Key "name" }} becomes {{ strictKey .Parent "name" }}
+func strictTemplates(set *template.Template) {
+	for _, tmpl := range set.Templates() {
+		if tmpl.Tree != nil && tmpl.Tree.Root != nil {
+			strictNode(tmpl.Tree, tmpl.Tree.Root)
+		}
+	}
+}
+
+func strictNode(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, sub := range n.Nodes {
+			strictNode(tree, sub)
+		}
+	case *parse.ActionNode:
+		strictNode(tree, n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			strictNode(tree, cmd)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			strictNode(tree, arg)
+		}
+		if len(n.Args) > 0 {
+			if call, recv := strictCall(tree, n.Args[0]); call != nil {
+				n.Args = append([]parse.Node{call, recv}, n.Args[1:]...)
+			}
+		}
+	case *parse.ChainNode:
+		strictNode(tree, n.Node)
+	case *parse.IfNode:
+		strictBranch(tree, &n.BranchNode)
+	case *parse.RangeNode:
+		strictBranch(tree, &n.BranchNode)
+	case *parse.WithNode:
+		strictBranch(tree, &n.BranchNode)
+	case *parse.TemplateNode:
+		strictNode(tree, n.Pipe)
+	}
+}
+
+func strictBranch(tree *parse.Tree, branch *parse.BranchNode) {
+	strictNode(tree, branch.Pipe)
+	strictNode(tree, branch.List)
+	strictNode(tree, branch.ElseList)
+}
+
+// strictCall of the method ending the field, variable or chain, and the
+// receiver of the method, both nil for other methods
+func strictCall(tree *parse.Tree, arg parse.Node) (call, recv parse.Node) {
+	var method string
+	switch n := arg.(type) {
+	case *parse.FieldNode:
+		last := len(n.Ident) - 1
+		method = n.Ident[last]
+		if last == 0 {
+			recv = &parse.DotNode{NodeType: parse.NodeDot, Pos: n.Pos}
+		} else {
+			field := n.Copy().(*parse.FieldNode)
+			field.Ident = field.Ident[:last]
+			recv = field
+		}
+	case *parse.VariableNode:
+		last := len(n.Ident) - 1
+		if last == 0 {
+			return nil, nil
+		}
+		method = n.Ident[last]
+		variable := n.Copy().(*parse.VariableNode)
+		variable.Ident = variable.Ident[:last]
+		recv = variable
+	case *parse.ChainNode:
+		last := len(n.Field) - 1
+		method = n.Field[last]
+		if last == 0 {
+			recv = n.Node
+		} else {
+			chain := n.Copy().(*parse.ChainNode)
+			chain.Field = chain.Field[:last]
+			recv = chain
+		}
+	default:
+		return nil, nil
+	}
+	name, ok := strictCalls[method]
+	if !ok {
+		return nil, nil
+	}
+	return parse.NewIdentifier(name).SetTree(tree).SetPos(arg.Position()), recv
+}
@@ -1,49 +1,150 @@
 package brief
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+)
 
 // ValueSpec states
 const (
-	NoKey  = "noKey"
-	NoVal  = "noVal"
-	NoCTX  = "noCTX"
-	NoName = "noName"
+	NoKey   = "noKey"
+	NoVal   = "noVal"
+	NoCTX   = "noCTX"
+	NoName  = "noName"
+	NoChild = "noChild"
+	NoSpec  = "noSpec"
+)
+
+// ValueSpec field selectors
+const (
+	ContentField = "@content"
+	NameField    = "@name"
 )
 
 // ValueSpec <elem>.<key> or <elem>.Name
+// The full value spec language is:
+//
+//	project               Name of the project context
+//	project.id            key id of the project context
+//	project.@content      Content of the project context
+//	project>settings.port key port of the settings child of the project context
+//	cmd.alias|cmd         key alias of cmd context or else the cmd Name
 type ValueSpec struct {
 	Elem, Name string
 	HasKey     bool
+	Content    bool       // select the Content instead of Name or key
+	Path       []string   // child node specs below the context
+	Alt        *ValueSpec // alternative when this spec has no value
+	Spec       string     // source of the value spec
+	err        error
+}
+
+// ValueError reports why a value spec has no value
+type ValueError struct {
+	Spec   string
+	Reason string // one of the ValueSpec states
+	Msg    string
+	Pos    scanner.Position // position of the node doing the lookup, if known
+}
+
+func (e *ValueError) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: value spec %q: %s", e.Pos, e.Spec, e.Msg)
+	}
+	return fmt.Sprintf("value spec %q: %s", e.Spec, e.Msg)
 }
 
 // NewValueSpec spec for a value Name or Key-value
 // <elem>.<key> or <elem>.Name
+// an invalid spec is reported when it is resolved
 func NewValueSpec(spec string) *ValueSpec {
-	elem, name, hasKey := ParseValueSpec(spec)
-	return &ValueSpec{
-		Elem:   elem,
-		Name:   name,
-		HasKey: hasKey,
+	val, err := CompileValueSpec(spec)
+	if err != nil {
+		elem, name, hasKey := ParseValueSpec(spec)
+		return &ValueSpec{Elem: elem, Name: name, HasKey: hasKey, Spec: spec, err: err}
 	}
+	return val
 }
 
-// Value from node if matching elem and has key
-func (val *ValueSpec) Value(node *Node) (string, bool) {
-	if val.Elem != node.Type {
-		return "", false
+// CompileValueSpec parses the full value spec language
+func CompileValueSpec(spec string) (*ValueSpec, error) {
+	var first, last *ValueSpec
+	for _, alt := range strings.Split(spec, "|") {
+		val, err := compileAlt(spec, strings.TrimSpace(alt))
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = val
+		} else {
+			last.Alt = val
+		}
+		last = val
 	}
-	if val.HasKey {
-		val, ok := node.Keys[val.Name]
-		return val, ok
+	return first, nil
+}
+
+func compileAlt(spec, alt string) (*ValueSpec, error) {
+	val := &ValueSpec{Name: NoKey, Spec: spec}
+	path := alt
+	if dot := strings.IndexRune(alt, '.'); dot >= 0 {
+		field := alt[dot+1:]
+		path = alt[:dot]
+		switch {
+		case strings.ContainsAny(field, ".>"):
+			return nil, specError(spec, "too many parts in %q, use > to select a child", alt)
+		case field == ContentField:
+			val.Content = true
+		case field == NameField:
+		case len(field) == 0:
+			return nil, specError(spec, "missing key after '.' in %q", alt)
+		case field[0] == '@':
+			return nil, specError(spec, "unknown field %s", field)
+		default:
+			val.Name = field
+			val.HasKey = true
+		}
 	}
-	if len(node.Name) == 0 {
-		return "", false
+	parts := strings.Split(path, ">")
+	for _, part := range parts {
+		if len(part) == 0 {
+			return nil, specError(spec, "missing element in %q", alt)
+		}
+	}
+	val.Elem = parts[0]
+	val.Path = parts[1:]
+	return val, nil
+}
+
+func specError(spec, format string, args ...interface{}) error {
+	return &ValueError{Spec: spec, Reason: NoSpec, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Value from node if matching elem and has key
+func (val *ValueSpec) Value(node *Node) (string, bool) {
+	for alt := val; alt != nil; alt = alt.Alt {
+		if !NewSpec(alt.Elem).Match(node) {
+			continue
+		}
+		at := node
+		if len(alt.Path) > 0 {
+			at = node.Child(alt.Path...)
+			if at == nil {
+				continue
+			}
+		}
+		found, err := alt.field(at)
+		if err == nil {
+			return found, true
+		}
 	}
-	return node.Name, true
+	return "", false
 }
 
 // ParseValueSpec type.key or type.Name
 // return type, value and hasKey
+// this only splits a simple spec, see CompileValueSpec for the full language
 func ParseValueSpec(spec string) (string, string, bool) {
 	values := strings.Split(spec, ".")
 	hasKey := len(values) > 1
@@ -57,25 +158,73 @@ func ParseValueSpec(spec string) (string, string, bool) {
 }
 
 // Lookup value spec in parents
+// returns one of the ValueSpec states when there is no value
 func (val *ValueSpec) Lookup(node *Node) string {
+	found, err := val.Resolve(node)
+	if err != nil {
+		return err.(*ValueError).Reason
+	}
+	return found
+}
+
+// Resolve value spec in parents
+// each alternative is tried in turn, the error is a *ValueError
+func (val *ValueSpec) Resolve(node *Node) (string, error) {
+	if val.err != nil {
+		return "", val.err
+	}
+	var err error
+	for alt := val; alt != nil; alt = alt.Alt {
+		var found string
+		found, err = alt.resolve(node)
+		if err == nil {
+			return found, nil
+		}
+	}
+	return "", err
+}
+
+func (val *ValueSpec) resolve(node *Node) (string, error) {
 	ctx := node.Context(val.Elem)
 	if ctx == nil {
-		return NoCTX
+		return "", val.errorf(NoCTX, "no context %s", val.Elem)
 	}
-	if val.HasKey {
-		kval, ok := ctx.Keys[val.Name]
+	if len(val.Path) > 0 {
+		child := ctx.Child(val.Path...)
+		if child == nil {
+			return "", val.errorf(NoChild, "no child %s in %s", strings.Join(val.Path, ">"), val.Elem)
+		}
+		ctx = child
+	}
+	return val.field(ctx)
+}
+
+// field selected by the spec from node
+func (val *ValueSpec) field(node *Node) (string, error) {
+	switch {
+	case val.Content:
+		if len(node.Content) == 0 {
+			return "", val.errorf(NoVal, "no content in %s", matchSpec(node))
+		}
+		return node.Content, nil
+	case val.HasKey:
+		kval, ok := node.Keys[val.Name]
 		if !ok {
-			return NoKey
+			return "", val.errorf(NoKey, "no key %s in %s", val.Name, matchSpec(node))
 		}
 		if len(kval) == 0 {
-			return NoVal
+			return "", val.errorf(NoVal, "key %s is empty in %s", val.Name, matchSpec(node))
 		}
-		return kval
+		return kval, nil
 	}
-	if len(ctx.Name) == 0 {
-		return NoName
+	if len(node.Name) == 0 {
+		return "", val.errorf(NoName, "no name for %s", node.Type)
 	}
-	return ctx.Name
+	return node.Name, nil
+}
+
+func (val *ValueSpec) errorf(reason, format string, args ...interface{}) error {
+	return &ValueError{Spec: val.Spec, Reason: reason, Msg: fmt.Sprintf(format, args...)}
 }
 
 // Collect value specs up the hierarchy into a Slice
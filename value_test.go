@@ -3,6 +3,7 @@ package brief_test
 import (
 	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/robbyriverside/brief"
 )
@@ -73,3 +74,204 @@ func TestLookupValue(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveValue(t *testing.T) {
+	tests := []struct {
+		Spec   string
+		Value  string
+		Reason string
+	}{
+		{Spec: "project>settings.port", Value: "8080"},
+		{Spec: "project>settings.host", Value: "localhost"},
+		{Spec: "command.@content", Value: "top level command"},
+		{Spec: "command.@name", Value: "top"},
+		{Spec: "command.alias|command", Value: "top"},
+		{Spec: "command.alias|project.version", Value: "1.0"},
+		{Spec: "project:peak.owner", Value: "ops"},
+		{Spec: "project>settings.missing", Reason: brief.NoKey},
+		{Spec: "project>nothing.port", Reason: brief.NoChild},
+		{Spec: "cli.port", Reason: brief.NoCTX},
+		{Spec: "project>settings.@content", Reason: brief.NoVal},
+		{Spec: "project.settings.port", Reason: brief.NoSpec},
+		{Spec: "project.", Reason: brief.NoSpec},
+		{Spec: "project>.port", Reason: brief.NoSpec},
+		{Spec: "project.@bogus", Reason: brief.NoSpec},
+	}
+	nodes, err := brief.Decode(strings.NewReader(base), "tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag := nodes[0].Find("flag:verbose")
+	for i, test := range tests {
+		res, err := brief.NewValueSpec(test.Spec).Resolve(flag)
+		if len(test.Reason) > 0 {
+			verr, ok := err.(*brief.ValueError)
+			if !ok {
+				t.Errorf("%d> %s expected %s error got %v", i, test.Spec, test.Reason, err)
+				continue
+			}
+			if verr.Reason != test.Reason {
+				t.Errorf("%d> %s failed %s != %s: %s", i, test.Spec, test.Reason, verr.Reason, verr)
+			}
+			if lookup := flag.Lookup(test.Spec); lookup != test.Reason {
+				t.Errorf("%d> %s lookup failed %s != %s", i, test.Spec, test.Reason, lookup)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d> %s failed: %s", i, test.Spec, err)
+			continue
+		}
+		if res != test.Value {
+			t.Errorf("%d> %s failed %s != %s", i, test.Spec, test.Value, res)
+		}
+	}
+}
+
+func TestLookupErr(t *testing.T) {
+	nodes, err := brief.DecodeFile("tests/base.brief")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag := nodes[0].Find("flag:verbose")
+	if _, err := flag.LookupErr("project.missing"); err == nil {
+		t.Error("missing key did not fail")
+	} else if !strings.HasPrefix(err.Error(), "tests/base.brief:4:9: ") {
+		t.Errorf("error not positioned: %s", err)
+	}
+	if _, err := flag.Require("project.version", "command"); err != nil {
+		t.Error(err)
+	}
+	if _, err := flag.Require("project.version", "cli"); err == nil {
+		t.Error("require did not fail")
+	}
+}
+
+func TestStrictTemplate(t *testing.T) {
+	dec, err := brief.NewFileDecoder("tests/base.brief")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec.Strict = true
+	nodes, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag := nodes[0].Find("flag:verbose")
+	tests := []struct {
+		Template string
+		Fail     bool
+	}{
+		{Template: `{{lookup "project.version" .}}`},
+		{Template: `{{lookup "project.missing" .}}`, Fail: true},
+		{Template: `{{key "short" .}}`},
+		{Template: `{{key "long" .}}`, Fail: true},
+		{Template: `{{join "/" . "project" "project.version"}}`},
+		{Template: `{{join "/" . "project" "project.missing"}}`, Fail: true},
+		{Template: `{{.Lookup "project.missing"}}`},
+		{Template: `{{.MustLookup "command.nothing"}}`, Fail: true},
+		{Template: `{{.Require "command.nothing"}}`, Fail: true},
+	}
+	for i, test := range tests {
+		tmpl := template.Must(template.New("strict").Funcs(brief.FuncMap()).Parse(test.Template))
+		var out strings.Builder
+		err := tmpl.Execute(&out, flag)
+		if test.Fail && err == nil {
+			t.Errorf("%d> %s did not fail: %q", i, test.Template, out.String())
+		}
+		if !test.Fail && err != nil {
+			t.Errorf("%d> %s failed: %s", i, test.Template, err)
+		}
+		if err != nil {
+			t.Logf("%d> %s", i, err)
+		}
+	}
+}
+
+func TestStrictRenderer(t *testing.T) {
+	tests := []struct {
+		Template, Out string
+	}{
+		{Template: `{{.Key "short"}}`, Out: "v"},
+		{Template: `{{.Key "long"}}`, Out: brief.NoKey},
+		{Template: `{{.Lookup "project.missing"}}`, Out: brief.NoKey},
+		{Template: `{{"project.missing" | .Lookup}}`, Out: brief.NoKey},
+		{Template: `{{.Parent.Lookup "project.missing"}}`, Out: brief.NoKey},
+		{Template: `{{(.Parent).Key "long"}}`, Out: brief.NoKey},
+		{Template: `{{$.Key "long"}}`, Out: brief.NoKey},
+		{Template: `{{with .Parent}}{{.Join "/" "project" "project.missing"}}{{end}}`, Out: "peak/" + brief.NoKey},
+		{Template: `{{range .Slice "project" "project.missing"}}{{.}};{{end}}`, Out: "peak;" + brief.NoKey + ";"},
+		{Template: `{{.Printf "%s-%s" "project" "project.missing"}}`, Out: "peak-" + brief.NoKey},
+		{Template: `{{template "partial" .}}`, Out: brief.NoKey},
+	}
+	for _, strict := range []bool{false, true} {
+		dec, err := brief.NewFileDecoder("tests/base.brief")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec.Strict = strict
+		nodes, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		flag := nodes[0].Find("flag:verbose")
+		partials := template.Must(template.New("partial").Parse(`{{.Key "long"}}`))
+		for i, test := range tests {
+			r := brief.NewRenderer()
+			if err := r.Parse("main", test.Template); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.AddTemplates(partials); err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			err := r.Render(&out, flag)
+			switch {
+			case strict && test.Out == "v":
+				if err != nil {
+					t.Errorf("%d> %s failed: %s", i, test.Template, err)
+				}
+			case strict:
+				if err == nil {
+					t.Errorf("%d> %s did not fail: %q", i, test.Template, out.String())
+				} else if !strings.Contains(err.Error(), "tests/base.brief:") {
+					t.Errorf("%d> error not positioned: %s", i, err)
+				}
+			case err != nil:
+				t.Errorf("%d> %s failed: %s", i, test.Template, err)
+			case out.String() != test.Out:
+				t.Errorf("%d> %s wrote %q not %q", i, test.Template, out.String(), test.Out)
+			}
+		}
+		// the partials of the decoder are not changed
+		var out strings.Builder
+		if err := partials.Execute(&out, flag); err != nil || out.String() != brief.NoKey {
+			t.Errorf("partial changed: %q %v", out.String(), err)
+		}
+	}
+}
+
+func TestStrictMethods(t *testing.T) {
+	dec, err := brief.NewFileDecoder("tests/base.brief")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec.Strict = true
+	nodes, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag := nodes[0].Find("flag:verbose")
+	// a strict node does not panic in the methods that return a state
+	if res := flag.Key("long"); res != brief.NoKey {
+		t.Errorf("strict Key %q != %q", res, brief.NoKey)
+	}
+	if res := flag.Lookup("project.missing"); res != brief.NoKey {
+		t.Errorf("strict Lookup %q != %q", res, brief.NoKey)
+	}
+	if _, err := flag.KeyErr("long"); err == nil {
+		t.Error("KeyErr did not fail")
+	} else if !strings.HasPrefix(err.Error(), "tests/base.brief:4:9: ") {
+		t.Errorf("error not positioned: %s", err)
+	}
+}
@@ -0,0 +1,139 @@
+package brief
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/scanner"
+)
+
+// define reads the value after the name of a #define or #set feature
+func (dec *Decoder) define(name string) {
+	dec.next()
+	sign := ""
+	if dec.ScanType == '-' && !dec.Text.LineStart {
+		sign = "-"
+		dec.next()
+	}
+	if dec.Text.LineStart || dec.ScanType == scanner.EOF {
+		dec.Errorf("#%s %s expects a value", dec.Feature, name)
+		return
+	}
+	var value string
+	switch dec.ScanType {
+	case scanner.String, scanner.RawString:
+		if len(sign) > 0 {
+			dec.Errorf("#%s %s invalid minus before string", dec.Feature, name)
+			return
+		}
+		dec.trimContentToken()
+		text, err := dec.interpolate(dec.Token)
+		if err != nil {
+			dec.Error(err.Error())
+			return
+		}
+		value = text
+	case scanner.Int, scanner.Float:
+		value = sign + dec.Token
+	case scanner.Ident:
+		if len(sign) > 0 {
+			dec.Errorf("#%s %s invalid minus before symbol", dec.Feature, name)
+			return
+		}
+		value = dec.Token
+	default:
+		dec.Errorf("#%s %s expects a value", dec.Feature, name)
+		return
+	}
+	dec.scope()[name] = value
+}
+
+// scope of the variables of this file, a copy of Vars made on first use so
+// definitions do not change Vars or the variables of an including file
+func (dec *Decoder) scope() map[string]string {
+	if dec.vars == nil {
+		dec.vars = make(map[string]string, len(dec.Vars))
+		for name, value := range dec.Vars {
+			dec.vars[name] = value
+		}
+	}
+	return dec.vars
+}
+
+// lookup a variable, or else the environment when EnvFallback is set
+func (dec *Decoder) lookup(name string) (string, bool) {
+	if value, ok := dec.scope()[name]; ok {
+		return value, true
+	}
+	if dec.EnvFallback {
+		return os.LookupEnv(name)
+	}
+	return "", false
+}
+
+// interpolate ${name} variables in text, $${ is a literal ${
+func (dec *Decoder) interpolate(text string) (string, error) {
+	return dec.expand(text, false)
+}
+
+// interpolateContent is interpolate for content, where an undefined or
+// unclosed ${ is kept as text since content is often shell or script code
+func (dec *Decoder) interpolateContent(text string) string {
+	text, _ = dec.expand(text, true)
+	return text
+}
+
+// expand the variables in text, keep leaves undefined ones as they are
+// instead of failing
+func (dec *Decoder) expand(text string, keep bool) (string, error) {
+	if !strings.Contains(text, "${") {
+		return text, nil
+	}
+	var out strings.Builder
+	for {
+		at := strings.Index(text, "${")
+		if at < 0 {
+			out.WriteString(text)
+			return out.String(), nil
+		}
+		if at > 0 && text[at-1] == '$' {
+			out.WriteString(text[:at-1] + "${")
+			text = text[at+2:]
+			continue
+		}
+		out.WriteString(text[:at])
+		end := strings.IndexRune(text[at:], '}')
+		if end < 0 {
+			if keep {
+				out.WriteString(text[at:])
+				return out.String(), nil
+			}
+			return "", fmt.Errorf("unclosed ${ in %q", text[at:])
+		}
+		name := text[at+2 : at+end]
+		value, ok := dec.lookup(name)
+		switch {
+		case ok:
+			out.WriteString(value)
+		case keep:
+			out.WriteString(text[at : at+end+1])
+		default:
+			return "", fmt.Errorf("undefined variable %s", name)
+		}
+		text = text[at+end+1:]
+	}
+}
+
+// unquote the token, the variables in a quoted token are replaced
+func (dec *Decoder) unquote() (string, bool) {
+	text := strings.Trim(dec.Token, "\"")
+	if len(dec.Token) == 0 || dec.Token[0] != '"' {
+		return text, true
+	}
+	text, err := dec.interpolate(text)
+	if err != nil {
+		dec.Error(err.Error())
+		return "", false
+	}
+	return text, true
+}
@@ -0,0 +1,106 @@
+package brief_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariables(t *testing.T) {
+	text := "#define module \"github.com/acme/tool\"\n" +
+		"#set version 1.2\n" +
+		"#define pkg \"${module}/pkg\"\n" +
+		"go:\"${module}\" version:\"v${version}\" path:\"${pkg}\" raw:version\n" +
+		"    doc `built from ${module} $${not} a variable`\n" +
+		"    block #|${version}|#\n"
+	dec := brief.NewDecoder(strings.NewReader(text), 4, "")
+	nodes, err := dec.Decode()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "github.com/acme/tool", nodes[0].Name)
+	assert.Equal(t, "v1.2", nodes[0].Keys["version"])
+	assert.Equal(t, "github.com/acme/tool/pkg", nodes[0].Keys["path"])
+	assert.Equal(t, "version", nodes[0].Keys["raw"], "identifiers are not interpolated")
+	assert.Equal(t, "built from github.com/acme/tool ${not} a variable", nodes[0].Child("doc").Content)
+	assert.Equal(t, "1.2", nodes[0].Child("block").Content)
+
+	_, err = brief.Decode(strings.NewReader("elem key:\"${missing}\"\n"), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "undefined variable missing")
+	_, err = brief.Decode(strings.NewReader("elem key:\"${open\"\n"), "")
+	assert.Error(t, err)
+	_, err = brief.Decode(strings.NewReader("#define\nelem\n"), "")
+	assert.Error(t, err, "define without a name")
+	_, err = brief.Decode(strings.NewReader("#define x\nelem\n"), "")
+	require.Error(t, err, "define without a value")
+	assert.Contains(t, err.Error(), "expects a value")
+	_, err = brief.Decode(strings.NewReader("#define x"), "")
+	assert.Error(t, err, "define without a value at the end")
+	_, err = brief.Decode(strings.NewReader("#define x -\nelem\n"), "")
+	assert.Error(t, err, "define with only a minus")
+	_, err = brief.Decode(strings.NewReader("#define x -name\n"), "")
+	assert.Error(t, err, "define with a minus before a symbol")
+}
+
+func TestVariablesContent(t *testing.T) {
+	// content is often shell or script code, its undefined variables stay
+	text := "script #| echo ${HOME} ${version} ${ |#\n" +
+		"    js `const s = '${x}' + \"${unclosed\"`\n"
+	dec := brief.NewDecoder(strings.NewReader(text), 4, "")
+	dec.Vars = map[string]string{"version": "1.2"}
+	nodes, err := dec.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, " echo ${HOME} 1.2 ${ ", nodes[0].Content)
+	assert.Equal(t, "const s = '${x}' + \"${unclosed\"", nodes[0].Child("js").Content)
+}
+
+func TestVariablesSigned(t *testing.T) {
+	text := "#define n -1\n#set f -2.5\nelem n:\"${n}\" f:\"${f}\" k:-5\n"
+	nodes, err := brief.Decode(strings.NewReader(text), "")
+	require.NoError(t, err)
+	assert.Equal(t, "-1", nodes[0].Keys["n"])
+	assert.Equal(t, "-2.5", nodes[0].Keys["f"])
+	assert.Equal(t, "-5", nodes[0].Keys["k"])
+}
+
+func TestVariablesPresetAndEnv(t *testing.T) {
+	vars := map[string]string{"env": "prod"}
+	dec := brief.NewDecoder(strings.NewReader("#define region eu\nelem name:\"${env}-${region}\"\n"), 4, "")
+	dec.Vars = vars
+	nodes, err := dec.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, "prod-eu", nodes[0].Keys["name"])
+	assert.Equal(t, map[string]string{"env": "prod"}, vars, "preset variables are not changed")
+
+	t.Setenv("BRIEF_TEST_HOME", "/home/brief")
+	text := "elem home:\"${BRIEF_TEST_HOME}\"\n"
+	_, err = brief.Decode(strings.NewReader(text), "")
+	assert.Error(t, err, "environment is opt-in")
+	dec = brief.NewDecoder(strings.NewReader(text), 4, "")
+	dec.EnvFallback = true
+	nodes, err = dec.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, "/home/brief", nodes[0].Keys["home"])
+}
+
+func TestVariablesIncludeScope(t *testing.T) {
+	files := fstest.MapFS{
+		"part.brief": {Data: []byte("#define local inner\npart module:\"${module}\" local:\"${local}\"\n")},
+	}
+	decode := func(text string) ([]*brief.Node, error) {
+		dec := brief.NewDecoder(strings.NewReader(text), 4, "/specs")
+		dec.Resolver = &brief.FSResolver{FS: files, Dir: "/specs"}
+		return dec.Decode()
+	}
+	nodes, err := decode("#define module acme\n#include `part.brief`\n")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", nodes[0].Keys["module"], "includes see the variables of the including file")
+	assert.Equal(t, "inner", nodes[0].Keys["local"])
+
+	_, err = decode("#define module acme\n#include `part.brief`\nafter local:\"${local}\"\n")
+	assert.Error(t, err, "definitions in an include stay in the include")
+}
@@ -0,0 +1,133 @@
+package brief
+
+// WalkAction controls a walk over the node hierarchy
+type WalkAction int
+
+// Walk actions
+const (
+	Continue     WalkAction = iota // Continue into the body
+	SkipChildren                   // SkipChildren of this node
+	Stop                           // Stop the walk
+)
+
+// WalkFunc is called for each node with its depth below the start node
+type WalkFunc func(node *Node, depth int) WalkAction
+
+// Walk the node and its body in pre-order
+// returns Stop if the walk was stopped
+func (node *Node) Walk(fn WalkFunc) WalkAction {
+	return node.walk(fn, 0)
+}
+
+func (node *Node) walk(fn WalkFunc, depth int) WalkAction {
+	switch fn(node, depth) {
+	case Stop:
+		return Stop
+	case SkipChildren:
+		return Continue
+	}
+	for _, sub := range node.Body {
+		if sub.walk(fn, depth+1) == Stop {
+			return Stop
+		}
+	}
+	return Continue
+}
+
+// Walk each of the nodes in order, such as the roots of a decode
+func Walk(nodes []*Node, fn WalkFunc) WalkAction {
+	for _, node := range nodes {
+		if node.Walk(fn) == Stop {
+			return Stop
+		}
+	}
+	return Continue
+}
+
+// Visitor is called before and after the body of each node
+type Visitor interface {
+	Enter(node *Node, depth int) WalkAction
+	Leave(node *Node, depth int) WalkAction
+}
+
+// Visit the node and its body calling Enter in pre-order and Leave in post-order
+// Leave is called even when Enter skips the children
+func (node *Node) Visit(v Visitor) WalkAction {
+	return node.visit(v, 0)
+}
+
+func (node *Node) visit(v Visitor, depth int) WalkAction {
+	switch v.Enter(node, depth) {
+	case Stop:
+		return Stop
+	case SkipChildren:
+	default:
+		for _, sub := range node.Body {
+			if sub.visit(v, depth+1) == Stop {
+				return Stop
+			}
+		}
+	}
+	if v.Leave(node, depth) == Stop {
+		return Stop
+	}
+	return Continue
+}
+
+// Iterator over the descendants of a node in pre-order
+type Iterator struct {
+	stack []iterFrame
+	node  *Node
+	depth int
+	skip  bool
+}
+
+type iterFrame struct {
+	body  []*Node
+	depth int
+}
+
+// Descendants iterates over the body of the node and its descendants
+//
+//	for it := node.Descendants(); it.Next(); {
+//	    use(it.Node())
+//	}
+func (node *Node) Descendants() *Iterator {
+	return &Iterator{stack: []iterFrame{{body: node.Body, depth: 1}}}
+}
+
+// Next advances to the next node, false when there are no more
+func (it *Iterator) Next() bool {
+	if it.node != nil && !it.skip && len(it.node.Body) > 0 {
+		it.stack = append(it.stack, iterFrame{body: it.node.Body, depth: it.depth + 1})
+	}
+	it.skip = false
+	for size := len(it.stack); size > 0; size = len(it.stack) {
+		top := &it.stack[size-1]
+		if len(top.body) == 0 {
+			it.stack = it.stack[:size-1]
+			continue
+		}
+		it.node = top.body[0]
+		it.depth = top.depth
+		top.body = top.body[1:]
+		return true
+	}
+	it.node = nil
+	return false
+}
+
+// Node at the current position
+func (it *Iterator) Node() *Node {
+	return it.node
+}
+
+// Depth of the current node below the start node
+func (it *Iterator) Depth() int {
+	return it.depth
+}
+
+// SkipChildren of the current node on the next call to Next
+func (it *Iterator) SkipChildren() {
+	it.skip = true
+}
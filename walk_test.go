@@ -0,0 +1,89 @@
+package brief_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader(test5), "tests")
+	require.NoError(t, err)
+	var types []string
+	var depths []int
+	nodes[0].Walk(func(node *brief.Node, depth int) brief.WalkAction {
+		types = append(types, node.Type)
+		depths = append(depths, depth)
+		return brief.Continue
+	})
+	assert.Equal(t, []string{"brevity", "project", "commands", "command", "commands", "command", "commands", "command"}, types)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, depths)
+
+	var names []string
+	nodes[0].Walk(func(node *brief.Node, depth int) brief.WalkAction {
+		if node.Name == "below" {
+			return brief.SkipChildren
+		}
+		if node.Type == "command" {
+			names = append(names, node.Name)
+		}
+		return brief.Continue
+	})
+	assert.Equal(t, []string{"top"}, names)
+
+	var count int
+	action := nodes[0].Walk(func(node *brief.Node, depth int) brief.WalkAction {
+		count++
+		if node.Type == "commands" {
+			return brief.Stop
+		}
+		return brief.Continue
+	})
+	assert.Equal(t, brief.Stop, action)
+	assert.Equal(t, 3, count)
+}
+
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) Enter(node *brief.Node, depth int) brief.WalkAction {
+	r.events = append(r.events, "+"+node.Type)
+	if node.Type == "head" {
+		return brief.SkipChildren
+	}
+	return brief.Continue
+}
+
+func (r *recorder) Leave(node *brief.Node, depth int) brief.WalkAction {
+	r.events = append(r.events, "-"+node.Type)
+	return brief.Continue
+}
+
+func TestVisit(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader(test0), "tests")
+	require.NoError(t, err)
+	var rec recorder
+	nodes[0].Visit(&rec)
+	assert.Equal(t, []string{
+		"+html", "+head", "-head",
+		"+body", "+h1", "-h1", "+div", "+p", "-p", "-div", "-body",
+		"-html",
+	}, rec.events)
+}
+
+func TestDescendants(t *testing.T) {
+	nodes, err := brief.Decode(strings.NewReader(test0), "tests")
+	require.NoError(t, err)
+	var types []string
+	for it := nodes[0].Descendants(); it.Next(); {
+		types = append(types, it.Node().Type)
+		if it.Node().Type == "div" {
+			it.SkipChildren()
+		}
+	}
+	assert.Equal(t, []string{"head", "title", "body", "h1", "div"}, types)
+}
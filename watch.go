@@ -0,0 +1,238 @@
+package brief
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Default Watcher timing
+const (
+	DefaultInterval = 250 * time.Millisecond
+	DefaultDebounce = 100 * time.Millisecond
+)
+
+// Watcher decodes files again when they or the files they include change
+// Files are polled for changes to their size or modification time, and a
+// burst of changes is handled once they have stopped for the Debounce time.
+type Watcher struct {
+	Files    []string      // brief files to decode
+	Extra    []string      // other files, such as templates, a change decodes every file
+	Globs    []string      // patterns of other files, matched again on each poll
+	Options  DecodeOptions // for each decode, the Context is set by Run
+	Interval time.Duration // between polls, DefaultInterval when zero
+	Debounce time.Duration // quiet time after a change, DefaultDebounce when zero
+	// Action is called with the results of the files decoded, first with
+	// every file and after that with the files changed
+	Action func(results []FileResult)
+	stamps map[string]stamp
+	uses   map[int]map[string]bool // index of a file to the paths it read in its last decode
+	extras map[string]bool         // the Extra and Globs paths since the last decode
+}
+
+// stamp of a file to notice changes
+type stamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statStamp(path string) stamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// NewWatcher of the brief files calling action after each decode
+func NewWatcher(files []string, action func(results []FileResult)) *Watcher {
+	return &Watcher{Files: files, Action: action}
+}
+
+// Run decodes the files and then watches for changes until ctx is done
+func (w *Watcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	debounce := w.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	w.Options.Context = ctx
+	all := make([]int, len(w.Files))
+	for i := range all {
+		all[i] = i
+	}
+	w.stamps = map[string]stamp{}
+	w.uses = map[int]map[string]bool{}
+	w.extras = w.extra()
+	for path := range w.extras {
+		w.stamps[path] = statStamp(path)
+	}
+	w.decode(all, nil)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	pending := map[string]bool{}
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if changed := w.Changed(); len(changed) > 0 {
+				for _, path := range changed {
+					pending[path] = true
+				}
+				last = now
+				continue
+			}
+			if len(pending) > 0 && now.Sub(last) >= debounce {
+				w.decode(w.affected(pending), pending)
+				pending = map[string]bool{}
+			}
+		}
+	}
+}
+
+// extra paths of the Extra files and the files matching the Globs
+func (w *Watcher) extra() map[string]bool {
+	paths := map[string]bool{}
+	for _, path := range w.Extra {
+		paths[absPath(path)] = true
+	}
+	for _, pattern := range w.Globs {
+		matches, _ := filepath.Glob(pattern) // only a bad pattern fails, it matches nothing
+		for _, path := range matches {
+			paths[absPath(path)] = true
+		}
+	}
+	return paths
+}
+
+// Changed returns the watched files changed since the last call, a file
+// newly matching the Globs is changed
+func (w *Watcher) Changed() []string {
+	if w.extras == nil {
+		w.extras = map[string]bool{}
+	}
+	for path := range w.extra() {
+		if !w.extras[path] {
+			w.extras[path] = true
+			if _, ok := w.stamps[path]; !ok {
+				w.stamps[path] = stamp{}
+			}
+		}
+	}
+	changed := make([]string, 0)
+	for path, old := range w.stamps {
+		if now := statStamp(path); now != old {
+			w.stamps[path] = now
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// affected files by the changed paths
+func (w *Watcher) affected(changed map[string]bool) []int {
+	for path := range changed {
+		if w.extras[path] {
+			all := make([]int, len(w.Files))
+			for at := range all {
+				all[at] = at
+			}
+			return all
+		}
+	}
+	files := make([]int, 0)
+	for at := range w.Files {
+		for path := range changed {
+			if w.uses[at][path] {
+				files = append(files, at)
+				break
+			}
+		}
+	}
+	return files
+}
+
+// decode the files, replace the paths they use and run the action
+// the paths known to be read are stamped before the decode so that a change
+// during the decode is seen by the next poll
+func (w *Watcher) decode(files []int, changed map[string]bool) {
+	if len(files) == 0 {
+		return
+	}
+	paths := make([]string, len(files))
+	before := map[string]stamp{}
+	for i, at := range files {
+		paths[i] = w.Files[at]
+		before[absPath(paths[i])] = statStamp(absPath(paths[i]))
+		for path := range w.uses[at] {
+			before[path] = statStamp(path)
+		}
+	}
+	if w.Options.Cache != nil {
+		for path := range changed {
+			w.Options.Cache.Invalidate(path)
+		}
+	}
+	start := time.Now()
+	results := DecodeFiles(paths, w.Options)
+	for i, result := range results {
+		used := map[string]bool{absPath(result.Path): true}
+		if len(result.SchemaFile) > 0 {
+			used[absPath(result.SchemaFile)] = true
+		}
+		for _, inc := range result.Includes {
+			used[absPath(inc.Path)] = true
+		}
+		w.uses[files[i]] = used
+		for path := range used {
+			if old, ok := before[path]; ok {
+				w.stamps[path] = old
+			} else if _, ok := w.stamps[path]; !ok {
+				w.stamps[path] = newStamp(path, start)
+			}
+		}
+	}
+	w.forget()
+	if w.Action != nil {
+		w.Action(results)
+	}
+}
+
+// newStamp of a path first read by a decode that started at start
+// a file modified since the start may have changed during the decode, so
+// it gets an empty stamp which the next poll reports as changed
+func newStamp(path string, start time.Time) stamp {
+	now := statStamp(path)
+	if now.exists && !now.modTime.Before(start) {
+		return stamp{}
+	}
+	return now
+}
+
+// forget the paths no longer used by a file, in Extra or matching the Globs
+func (w *Watcher) forget() {
+	w.extras = w.extra()
+	keep := map[string]bool{}
+	for path := range w.extras {
+		keep[path] = true
+	}
+	for _, used := range w.uses {
+		for path := range used {
+			keep[path] = true
+		}
+	}
+	for path := range w.stamps {
+		if !keep[path] {
+			delete(w.stamps, path)
+		}
+	}
+}
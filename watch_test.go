@@ -0,0 +1,180 @@
+package brief_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robbyriverside/brief"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writer of files in dir, each write has a new modification time even on a
+// coarse file system, in the past so that it is before any decode
+func writer(t *testing.T, dir string) func(name, text string) {
+	base := time.Now().Add(-time.Hour)
+	touch := 0
+	return func(name, text string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(text), 0644))
+		touch++
+		at := base.Add(time.Duration(touch) * time.Second)
+		require.NoError(t, os.Chtimes(path, at, at))
+	}
+}
+
+// watch runs the watcher until the test ends, next returns the results of
+// a decode and quiet checks there is none for a while
+func watch(t *testing.T, w *brief.Watcher) (next func() []brief.FileResult, quiet func()) {
+	decoded := make(chan []brief.FileResult, 10)
+	w.Action = func(results []brief.FileResult) { decoded <- results }
+	w.Interval = 5 * time.Millisecond
+	w.Debounce = 20 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	next = func() []brief.FileResult {
+		select {
+		case results := <-decoded:
+			return results
+		case <-time.After(5 * time.Second):
+			t.Fatal("no decode")
+		}
+		return nil
+	}
+	quiet = func() {
+		select {
+		case results := <-decoded:
+			t.Fatalf("unexpected decode of %s", results[0].Path)
+		case <-time.After(150 * time.Millisecond):
+		}
+	}
+	return next, quiet
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := writer(t, dir)
+	write("header.brief", "header:v1\n")
+	write("a.brief", "page:a\n    #include `header.brief`\n")
+	write("b.brief", "page:b\n")
+
+	w := brief.NewWatcher([]string{filepath.Join(dir, "a.brief"), filepath.Join(dir, "b.brief")}, nil)
+	w.Options.Cache = brief.NewIncludeCache()
+	next, quiet := watch(t, w)
+	require.Len(t, next(), 2, "first decode has every file")
+
+	write("header.brief", "header:v2\n")
+	write("header.brief", "header:v3\n") // a burst is one decode
+	results := next()
+	require.Len(t, results, 1)
+	assert.Equal(t, filepath.Join(dir, "a.brief"), results[0].Path)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "v3", results[0].Nodes[0].Child("header").Name)
+
+	write("b.brief", "page:b2\n")
+	results = next()
+	require.Len(t, results, 1)
+	assert.Equal(t, "b2", results[0].Nodes[0].Name)
+	quiet()
+}
+
+func TestWatcherRemovedInclude(t *testing.T) {
+	dir := t.TempDir()
+	write := writer(t, dir)
+	write("header.brief", "header:v1\n")
+	write("a.brief", "page:a\n    #include `header.brief`\n")
+	next, quiet := watch(t, brief.NewWatcher([]string{filepath.Join(dir, "a.brief")}, nil))
+	require.Len(t, next(), 1)
+
+	write("a.brief", "page:a\n")
+	results := next()
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Includes)
+
+	write("header.brief", "header:v2\n")
+	quiet()
+}
+
+// hookResolver opens files after calling the hook
+type hookResolver struct {
+	hook func(path string)
+}
+
+func (r *hookResolver) Open(path string) (io.ReadCloser, error) {
+	r.hook(path)
+	return os.Open(path)
+}
+
+func TestWatcherChangeDuringDecode(t *testing.T) {
+	dir := t.TempDir()
+	write := writer(t, dir)
+	write("header.brief", "header:v1\n")
+	write("a.brief", "page:a\n    #include `header.brief`\n")
+	header := filepath.Join(dir, "header.brief")
+	edits := []string{"header:v2\n", "header:v3\n"}
+	w := brief.NewWatcher([]string{filepath.Join(dir, "a.brief")}, nil)
+	w.Options.Resolver = &hookResolver{hook: func(path string) {
+		if len(edits) == 0 {
+			return
+		}
+		// edited after the decode read the including file, with a
+		// modification time that is later than any stamp taken before
+		text := edits[0]
+		edits = edits[1:]
+		at := time.Now().Add(time.Duration(len(edits)+1) * time.Minute)
+		assert.NoError(t, os.WriteFile(header, []byte(text), 0644))
+		assert.NoError(t, os.Chtimes(header, at, at))
+	}}
+	next, quiet := watch(t, w)
+	// the include is new to the first decode
+	assert.Equal(t, "v2", next()[0].Nodes[0].Child("header").Name)
+	// and known to the second
+	assert.Equal(t, "v3", next()[0].Nodes[0].Child("header").Name)
+	assert.Equal(t, "v3", next()[0].Nodes[0].Child("header").Name)
+	quiet()
+}
+
+func TestWatcherSchema(t *testing.T) {
+	dir := t.TempDir()
+	write := writer(t, dir)
+	write("app.schema.brief", "schema:app\n")
+	write("a.brief", "#schema `app.schema.brief`\napp:a\n")
+	next, quiet := watch(t, brief.NewWatcher([]string{filepath.Join(dir, "a.brief")}, nil))
+	require.Len(t, next(), 1)
+
+	write("app.schema.brief", "schema:app2\n")
+	results := next()
+	require.Len(t, results, 1)
+	assert.Equal(t, filepath.Join(dir, "app.schema.brief"), results[0].SchemaFile)
+	quiet()
+}
+
+func TestWatcherGlobs(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "tmpl")
+	require.NoError(t, os.Mkdir(tmpl, 0755))
+	write := writer(t, dir)
+	write("a.brief", "page:a\n")
+	write("b.brief", "page:b\n")
+	w := brief.NewWatcher([]string{filepath.Join(dir, "a.brief"), filepath.Join(dir, "b.brief")}, nil)
+	w.Globs = []string{filepath.Join(tmpl, "*")}
+	next, quiet := watch(t, w)
+	require.Len(t, next(), 2)
+
+	write("tmpl/page.tmpl", "{{.Name}}")
+	require.Len(t, next(), 2, "a new template decodes every file")
+	write("tmpl/page.tmpl", "{{.Type}}")
+	require.Len(t, next(), 2, "a changed template decodes every file")
+	require.NoError(t, os.Remove(filepath.Join(tmpl, "page.tmpl")))
+	require.Len(t, next(), 2, "a removed template decodes every file")
+	quiet()
+}
@@ -2,18 +2,147 @@ package brief
 
 import (
 	_ "embed"
+	"fmt"
 	"io"
+	"strconv"
 	"text/template"
+	"unicode"
 )
 
 //go:embed templates/xmlout.tmpl
 var xmlout string
 
+//go:embed templates/xsd.tmpl
+var xsdout string
+
 // WriteXML for a Node to a writer
+// The name of a node is the name attribute, so a name key is not written
+// when the node has a name.  An element type or key that is not an XML
+// name is an error.
 func (node *Node) WriteXML(out io.Writer) error {
+	if err := xmlNames(node); err != nil {
+		return err
+	}
 	tmpl, err := template.New("xmlout").Parse(xmlout)
 	if err != nil {
 		return err
 	}
 	return tmpl.Execute(out, node)
 }
+
+// xmlNames checks the element types and keys of the node and its body
+func xmlNames(node *Node) error {
+	var err error
+	node.Walk(func(n *Node, depth int) WalkAction {
+		if !xmlName(n.Type) {
+			err = fmt.Errorf("%s: element type %q is not an XML name", n.Pos, n.Type)
+			return Stop
+		}
+		for key := range n.Keys {
+			if !xmlName(key) {
+				err = fmt.Errorf("%s: key %q is not an XML name", n.Pos, key)
+				return Stop
+			}
+		}
+		return Continue
+	})
+	return err
+}
+
+// xmlName true for a name that can be an XML element or attribute name
+// without a namespace prefix
+func xmlName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return len(name) > 0
+}
+
+// xsdTypes is the view of a schema used by the xsd template
+type xsdTypes struct {
+	Roots []string
+	Types []*xsdType
+}
+
+type xsdType struct {
+	Type     string
+	Open     bool
+	Single   bool // one child type so cardinality is exact
+	Children []*xsdChild
+	Attrs    []*xsdAttr
+}
+
+type xsdChild struct {
+	Type, Min, Max string
+}
+
+type xsdAttr struct {
+	Name, Type string
+	Required   bool
+}
+
+var xsdValueTypes = map[string]string{
+	TypeString: "xs:string",
+	TypeIdent:  "xs:NCName",
+	TypeInt:    "xs:integer",
+	TypeFloat:  "xs:double",
+	TypeBool:   "xs:boolean",
+}
+
+// WriteXSD writes an XML Schema for the WriteXML output of documents in the schema
+// The name and keys become attributes and children become elements.
+// Child cardinality is exact when an element has one child type,
+// otherwise any number of the child types are allowed in any order.
+// An element type or key that is not an XML name is an error.
+func (schema *Schema) WriteXSD(out io.Writer) error {
+	for _, elem := range schema.Elements {
+		if !xmlName(elem.Type) {
+			return fmt.Errorf("element type %q is not an XML name", elem.Type)
+		}
+		for _, key := range elem.Keys {
+			if !xmlName(key.Name) {
+				return fmt.Errorf("element %s key %q is not an XML name", elem.Type, key.Name)
+			}
+		}
+	}
+	view := &xsdTypes{}
+	for _, elem := range schemaRoots(schema) {
+		view.Roots = append(view.Roots, elem.Type)
+	}
+	for _, elem := range schema.Elements {
+		typ := &xsdType{Type: elem.Type, Open: elem.Open}
+		if elem.Name != nil {
+			typ.Attrs = append(typ.Attrs, &xsdAttr{Name: "name", Type: xsdValueTypes[elem.Name.Type], Required: elem.Name.Required})
+		} else {
+			typ.Attrs = append(typ.Attrs, &xsdAttr{Name: "name", Type: xsdValueTypes[TypeString]})
+		}
+		for _, key := range elem.Keys {
+			if key.Name == "name" {
+				continue
+			}
+			typ.Attrs = append(typ.Attrs, &xsdAttr{Name: key.Name, Type: xsdValueTypes[key.Type], Required: key.Required})
+		}
+		for _, child := range elem.Children {
+			if schema.Element(child.Type) == nil {
+				continue
+			}
+			max := "unbounded"
+			if child.Max >= 0 {
+				max = strconv.Itoa(child.Max)
+			}
+			typ.Children = append(typ.Children, &xsdChild{Type: child.Type, Min: strconv.Itoa(child.Min), Max: max})
+		}
+		typ.Single = len(typ.Children) == 1
+		view.Types = append(view.Types, typ)
+	}
+	tmpl, err := template.New("xsd").Parse(xsdout)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, view)
+}